@@ -0,0 +1,220 @@
+// Package assets 静态文件指纹，生成带内容哈希的 URL，带指纹的文件使用长期缓存
+//
+//	a, err := assets.New("/static", "./public")
+//	view.Func(a.FuncMap())
+//	app.GET("/static/{filepath:*}", a.Handler())
+//
+// 视图中 {{ asset "css/app.css" }} 输出 /static/css/app.3f9a1c.css
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/icodechef/potgo"
+)
+
+// ImmutableCacheControl 带指纹的文件使用的 Cache-Control
+const ImmutableCacheControl = "public, max-age=31536000, immutable"
+
+// hashLength 文件名中内容哈希的长度
+const hashLength = 6
+
+// Assets 静态文件指纹
+type Assets struct {
+	mu       sync.RWMutex
+	fsys     fs.FS
+	prefix   string
+	paths    map[string]string // 文件名 -> 带指纹的文件名
+	files    map[string]string // 带指纹的文件名 -> 文件名
+	manifest string
+}
+
+// New 计算 root 目录下所有文件的内容哈希，prefix 为静态文件的 URL 前缀，例如 /static
+func New(prefix string, root string) (*Assets, error) {
+	return NewFS(prefix, os.DirFS(root))
+}
+
+// NewFS 计算 fs.FS 中所有文件的内容哈希，例如 embed.FS
+func NewFS(prefix string, fsys fs.FS) (*Assets, error) {
+	a := &Assets{
+		fsys:   fsys,
+		prefix: "/" + strings.Trim(prefix, "/"),
+	}
+	if err := a.Load(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Load 重新计算所有文件的内容哈希，设置了 manifest 时重新读取 manifest
+func (a *Assets) Load() error {
+	paths := make(map[string]string)
+	files := make(map[string]string)
+	err := fs.WalkDir(a.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 忽略隐藏的文件和目录
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		hash, err := hashFile(a.fsys, name)
+		if err != nil {
+			return err
+		}
+		fingerprinted := fingerprint(name, hash)
+		paths[name] = fingerprinted
+		files[fingerprinted] = name
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.paths, a.files = paths, files
+	manifest := a.manifest
+	a.mu.Unlock()
+
+	if manifest != "" {
+		return a.LoadManifest(manifest)
+	}
+	return nil
+}
+
+// LoadManifest 读取前端构建工具生成的 manifest，name 为 fs.FS 中的文件名
+//
+// manifest 为 JSON 对象，键为文件名，值为构建后的文件名，或者是包含 file 字段的对象，例如
+//
+//	{"css/app.css": "css/app.5d41402a.css"}
+//	{"src/main.js": {"file": "assets/main.4b2e1f.js"}}
+//
+// 构建后的文件名相对于静态文件目录，manifest 中的文件优先于计算内容哈希的文件，并同样使用长期缓存
+func (a *Assets) LoadManifest(name string) error {
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return err
+	}
+	var entries map[string]json.RawMessage
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("assets: invalid manifest %s: %w", name, err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.manifest = name
+	for key, raw := range entries {
+		var file string
+		if err = json.Unmarshal(raw, &file); err != nil {
+			var entry struct {
+				File string `json:"file"`
+			}
+			if err = json.Unmarshal(raw, &entry); err != nil {
+				return fmt.Errorf("assets: invalid manifest entry %q: %w", key, err)
+			}
+			file = entry.File
+		}
+		file = a.relative(file)
+		if file == "" {
+			continue
+		}
+		a.paths[a.relative(key)] = file
+		a.files[file] = file
+	}
+	return nil
+}
+
+// relative 去掉 URL 前缀和开头的 /
+func (a *Assets) relative(name string) string {
+	if strings.HasPrefix(name, a.prefix+"/") {
+		name = name[len(a.prefix):]
+	}
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Path 返回带指纹的文件名，未知的文件返回原文件名
+func (a *Assets) Path(name string) string {
+	name = a.relative(name)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if p, ok := a.paths[name]; ok {
+		return p
+	}
+	return name
+}
+
+// URL 返回带指纹的 URL，例如 /static/css/app.3f9a1c.css
+func (a *Assets) URL(name string) string {
+	return path.Join(a.prefix, a.Path(name))
+}
+
+// FuncMap 返回包含 asset 函数的视图函数
+//
+//	<link rel="stylesheet" href="{{ asset "css/app.css" }}">
+func (a *Assets) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset": a.URL,
+	}
+}
+
+// Handler 返回提供静态文件的 HandlerFunc，路由必须包含 {filepath:*} 参数
+//
+// 带指纹的文件使用 ImmutableCacheControl，其它文件与 Router.Static 相同
+func (a *Assets) Handler() potgo.HandlerFunc {
+	return func(c *potgo.Context) error {
+		name := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+
+		a.mu.RLock()
+		file, ok := a.files[name]
+		a.mu.RUnlock()
+		if !ok {
+			return c.FileFS(a.fsys, name)
+		}
+
+		header := c.Response.Header()
+		header.Set("Cache-Control", ImmutableCacheControl)
+		err := c.FileFS(a.fsys, file)
+		if err != nil {
+			header.Del("Cache-Control")
+		}
+		return err
+	}
+}
+
+// hashFile 返回文件内容的 SHA-256 哈希的前 hashLength 个十六进制字符
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:hashLength], nil
+}
+
+// fingerprint 在扩展名之前插入哈希，例如 css/app.css 返回 css/app.3f9a1c.css
+func fingerprint(name string, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
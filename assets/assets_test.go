@@ -0,0 +1,93 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"css/app.css":                {Data: []byte("body{}")},
+		"js/app.js":                  {Data: []byte("alert(1)")},
+		"LICENSE":                    {Data: []byte("MIT")},
+		".vite/manifest.json":        {Data: []byte(`{"src/main.ts": {"file": "build/main.4b2e1f.js"}, "logo.svg": "/static/build/logo.9a8b7c.svg"}`)},
+		"build/main.4b2e1f.js":       {Data: []byte("main")},
+		"build/logo.9a8b7c.svg":      {Data: []byte("<svg/>")},
+		"broken/manifest.json":       {Data: []byte(`[1]`)},
+		"broken/manifest_entry.json": {Data: []byte(`{"a": 1}`)},
+	}
+}
+
+func TestAssets(t *testing.T) {
+	a, err := NewFS("static/", testFS())
+	assert.Nil(t, err)
+
+	css := "/static/css/app." + hashOf("body{}") + ".css"
+	assert.Equal(t, css, a.URL("css/app.css"))
+	assert.Equal(t, css, a.URL("/css/app.css"))
+	assert.Equal(t, "/static/LICENSE."+hashOf("MIT"), a.URL("LICENSE"))
+	assert.Equal(t, "/static/unknown.css", a.URL("unknown.css"))
+	assert.Equal(t, "/static/.vite/manifest.json", a.URL(".vite/manifest.json"))
+
+	assert.Nil(t, a.LoadManifest(".vite/manifest.json"))
+	assert.Equal(t, "/static/build/main.4b2e1f.js", a.URL("src/main.ts"))
+	assert.Equal(t, "build/logo.9a8b7c.svg", a.Path("logo.svg"))
+
+	// 重新加载时同样读取 manifest
+	assert.Nil(t, a.Load())
+	assert.Equal(t, "/static/build/main.4b2e1f.js", a.URL("src/main.ts"))
+
+	assert.NotNil(t, a.LoadManifest("missing.json"))
+	assert.NotNil(t, a.LoadManifest("broken/manifest.json"))
+	assert.NotNil(t, a.LoadManifest("broken/manifest_entry.json"))
+}
+
+func TestAssets_Handler(t *testing.T) {
+	a, _ := NewFS("/static", testFS())
+	_ = a.LoadManifest(".vite/manifest.json")
+
+	app := potgo.New()
+	view := potgo.HTMLFS(fstest.MapFS{
+		"index.html": {Data: []byte(`<link href="{{ asset "css/app.css" }}">`)},
+	}, ".", ".html")
+	view.Func(a.FuncMap())
+	_ = app.RegisterView(view)
+
+	app.GET("/", func(c *potgo.Context) error {
+		return c.View("index.html")
+	})
+	app.GET("/static/{filepath:*}", a.Handler())
+
+	tests := []struct {
+		path         string
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{"/", http.StatusOK, `<link href="/static/css/app.` + hashOf("body{}") + `.css">`, ""},
+		{"/static/css/app." + hashOf("body{}") + ".css", http.StatusOK, "body{}", ImmutableCacheControl},
+		{"/static/css/app.css", http.StatusOK, "body{}", ""},
+		{"/static/build/main.4b2e1f.js", http.StatusOK, "main", ImmutableCacheControl},
+		{"/static/css/app.000000.css", http.StatusNotFound, "Not Found\n", ""},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, tt.status, res.Code, tt.path)
+		assert.Equal(t, tt.body, res.Body.String(), tt.path)
+		assert.Equal(t, tt.cacheControl, res.Header().Get("Cache-Control"), tt.path)
+	}
+}
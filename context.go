@@ -1,20 +1,24 @@
 package potgo
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime/multipart"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const defaultMemory = 32 << 20 // 32 MB
@@ -29,29 +33,186 @@ type Context struct {
 	Response      Response
 	index         int
 	mu            sync.RWMutex
-	data          map[string]interface{}
+	data          map[interface{}]interface{}
 	queryCache    url.Values
 	postFormCache url.Values
 	formCache     url.Values
 	viewData      map[string]interface{}
 	viewLayout    string
+	route         *Route
+	flash         *flash
+	copied        bool
+	released      int32
+	refs          int32    // 当前请求和尚未结束的 Fork 的数量，为 0 时才能放回对象池
+	parent        *Context // Fork 返回的上下文所属的原上下文
+	err           error    // 交给错误处理程序的错误
 }
 
 func (c *Context) reset(w http.ResponseWriter, r *http.Request) {
 	c.Response.reset(w)
+	c.Response.after = nil
 	c.Request = r
 	c.handlers = nil
 	c.pKeys = c.pKeys[0:0]
 	c.index = -1
+	c.data = nil
+	c.route = nil
+	c.flash = nil
 	c.queryCache = nil
 	c.postFormCache = nil
 	c.formCache = nil
 	c.viewData = nil
 	c.viewLayout = ""
+	c.refs = 1
+	c.parent = nil
+	c.err = nil
+}
+
+// Copy 返回当前上下文的只读副本
+//
+// 副本包含请求、路径参数、上下文中保存的数据和匹配的路由，在 HandlerFunc 返回后仍然可以安全使用，
+// 在 goroutine 中使用上下文时必须使用副本。副本不能向客户端写入数据
+func (c *Context) Copy() *Context {
+	cp := c.clone(copiedWriter{})
+	cp.copied = true
+	return cp
+}
+
+// Fork 返回一个使用 w 写入响应的新上下文，新上下文可以调用 Next 继续执行剩余的 HandlerFunc
+//
+// 新上下文不会放回对象池，可以在 goroutine 中执行剩余的 HandlerFunc，例如超时中间件。
+// 新上下文使用完毕后必须调用 Release，在此之前原上下文不会放回对象池
+func (c *Context) Fork(w http.ResponseWriter) *Context {
+	f := c.clone(w)
+	f.parent = c
+	atomic.AddInt32(&c.refs, 1)
+	f.handlers = c.handlers
+	f.index = c.index
+	f.viewLayout = c.viewLayout
+	f.Response.status = c.Response.status
+
+	c.mu.RLock()
+	if c.viewData != nil {
+		f.viewData = make(map[string]interface{}, len(c.viewData))
+		for k, v := range c.viewData {
+			f.viewData[k] = v
+		}
+	}
+	c.mu.RUnlock()
+
+	return f
+}
+
+// Merge 把 Fork 返回的上下文中保存的数据、视图数据、视图布局和闪存数据合并到当前上下文
+//
+// f 中还没有执行的 Response.Before 和 Response.After 函数会立即执行，响应头由调用者从 f 使用的 http.ResponseWriter 中复制
+func (c *Context) Merge(f *Context) {
+	c.checkReleased()
+	f.checkReleased()
+
+	f.mu.RLock()
+	data, viewData := f.data, f.viewData
+	f.mu.RUnlock()
+
+	c.mu.Lock()
+	if len(data) > 0 && c.data == nil {
+		c.data = make(map[interface{}]interface{}, len(data))
+	}
+	for k, v := range data {
+		c.data[k] = v
+	}
+	if len(viewData) > 0 && c.viewData == nil {
+		c.viewData = make(map[string]interface{}, len(viewData))
+	}
+	for k, v := range viewData {
+		c.viewData[k] = v
+	}
+	c.mu.Unlock()
+
+	c.viewLayout = f.viewLayout
+	c.mergeFlash(f)
+	f.Response.runBefore()
+	f.Response.runAfter()
+}
+
+// clone 复制请求、路径参数、上下文中保存的数据和匹配的路由
+func (c *Context) clone(w http.ResponseWriter) *Context {
+	c.checkReleased()
+
+	cp := &Context{
+		app:     c.app,
+		Request: c.Request,
+		pKeys:   make([]string, len(c.pKeys)),
+		pValues: make([]string, len(c.pKeys)),
+		route:   c.route,
+	}
+	copy(cp.pKeys, c.pKeys)
+	copy(cp.pValues, c.pValues)
+	cp.Response.reset(w)
+
+	c.mu.RLock()
+	if c.data != nil {
+		cp.data = make(map[interface{}]interface{}, len(c.data))
+		for k, v := range c.data {
+			cp.data[k] = v
+		}
+	}
+	c.mu.RUnlock()
+
+	return cp
+}
+
+// IsCopy 是否为 Copy 返回的副本
+func (c *Context) IsCopy() bool {
+	return c.copied
+}
+
+// Route 返回当前请求匹配的路由，未匹配时返回 nil
+func (c *Context) Route() *Route {
+	return c.route
+}
+
+// Release 结束 Fork 返回的上下文，之后继续使用该上下文会 panic
+//
+// 请求已经结束并且所有 Fork 返回的上下文都已结束时，原上下文放回对象池
+func (c *Context) Release() {
+	p := c.parent
+	if p == nil || atomic.LoadInt32(&c.released) == 1 {
+		return
+	}
+	c.parent = nil
+	c.release()
+	p.done()
+}
+
+// done 结束当前请求或者一个 Fork 返回的上下文，全部结束后把上下文放回对象池
+func (c *Context) done() {
+	if atomic.AddInt32(&c.refs, -1) > 0 {
+		return
+	}
+	if c.app == nil || c.app.debug {
+		c.release()
+		return
+	}
+	c.app.pool.Put(c)
+}
+
+// release 将上下文标记为已释放
+func (c *Context) release() {
+	atomic.StoreInt32(&c.released, 1)
+	c.Response.Writer = releasedWriter{}
+}
+
+// checkReleased 检查上下文是否已经释放
+func (c *Context) checkReleased() {
+	if atomic.LoadInt32(&c.released) == 1 {
+		panic(errContextReleased)
+	}
 }
 
 // Next 调用与当前路由关联的其它 HandlerFunc
 func (c *Context) Next() error {
+	c.checkReleased()
 	c.index++
 	if c.index < len(c.handlers) {
 		if err := c.handlers[c.index](c); err != nil {
@@ -73,28 +234,136 @@ func (c *Context) IsAborted() bool {
 
 // Set 在上下文中保存数据
 func (c *Context) Set(key string, value interface{}) {
+	c.setValue(key, value)
+}
+
+// Get 从上下文中检索数据
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	return c.value(key)
+}
+
+// MustGet 从上下文中检索数据，如果不存在则 panic
+func (c *Context) MustGet(key string) interface{} {
+	value, err := c.lookup(key)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// GetString 以 string 类型从上下文中检索数据
+func (c *Context) GetString(key string) (value string, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetInt 以 int 类型从上下文中检索数据
+func (c *Context) GetInt(key string) (value int, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetInt64 以 int64 类型从上下文中检索数据
+func (c *Context) GetInt64(key string) (value int64, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetFloat64 以 float64 类型从上下文中检索数据
+func (c *Context) GetFloat64(key string) (value float64, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetBool 以 bool 类型从上下文中检索数据
+func (c *Context) GetBool(key string) (value bool, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetTime 以 time.Time 类型从上下文中检索数据
+func (c *Context) GetTime(key string) (value time.Time, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetDuration 以 time.Duration 类型从上下文中检索数据
+func (c *Context) GetDuration(key string) (value time.Duration, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetStringSlice 以 []string 类型从上下文中检索数据
+func (c *Context) GetStringSlice(key string) (value []string, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+// GetStringMap 以 map[string]interface{} 类型从上下文中检索数据
+func (c *Context) GetStringMap(key string) (value map[string]interface{}, err error) {
+	err = c.scan(key, &value)
+	return
+}
+
+func (c *Context) setValue(key, value interface{}) {
+	c.checkReleased()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.data == nil {
-		c.data = make(map[string]interface{})
+		c.data = make(map[interface{}]interface{})
 	}
 	c.data[key] = value
 }
 
-// Get 从上下文中检索数据
-func (c *Context) Get(key string) (value interface{}, exists bool) {
+func (c *Context) value(key interface{}) (value interface{}, exists bool) {
+	c.checkReleased()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	value, exists = c.data[key]
 	return
 }
 
+// lookup 检索数据，不存在时返回错误
+func (c *Context) lookup(key interface{}) (interface{}, error) {
+	value, ok := c.value(key)
+	if !ok {
+		return nil, &KeyError{Key: fmt.Sprint(key)}
+	}
+	return value, nil
+}
+
+// scan 检索数据并赋值给 ptr 指向的变量，数据不存在或类型不匹配时返回错误
+func (c *Context) scan(key interface{}, ptr interface{}) error {
+	value, err := c.lookup(key)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("ptr must be a pointer")
+	}
+	elem := rv.Elem()
+
+	vv := reflect.ValueOf(value)
+	if !vv.IsValid() {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+	if !vv.Type().AssignableTo(elem.Type()) {
+		return &KeyError{Key: fmt.Sprint(key), Type: elem.Type().String(), Actual: vv.Type().String()}
+	}
+	elem.Set(vv)
+	return nil
+}
+
 //  +-----------------------------------------------------------+
 //  | Request and Post Data                                     |
 //  +-----------------------------------------------------------+
 
 // Param 获取路径中的参数
 func (c *Context) Param(key string) string {
+	c.checkReleased()
 	for i, n := range c.pKeys {
 		if n == key {
 			return c.pValues[i]
@@ -104,6 +373,7 @@ func (c *Context) Param(key string) string {
 }
 
 func (c *Context) getQuery() url.Values {
+	c.checkReleased()
 	if c.queryCache == nil {
 		c.queryCache = c.Request.URL.Query()
 	}
@@ -179,6 +449,7 @@ func (c *Context) PostValueMap(key string) map[string]string {
 }
 
 func (c *Context) getPostForm() url.Values {
+	c.checkReleased()
 	if c.postFormCache == nil {
 		_ = c.Request.ParseMultipartForm(defaultMemory) // 32M
 		c.postFormCache = c.Request.PostForm
@@ -221,6 +492,7 @@ func (c *Context) FormValueMap(key string) map[string]string {
 }
 
 func (c *Context) getForm() url.Values {
+	c.checkReleased()
 	if c.formCache == nil {
 		_ = c.Request.ParseMultipartForm(defaultMemory) // 32M
 		c.formCache = c.Request.Form
@@ -445,21 +717,6 @@ func storeFormFile(file *multipart.FileHeader, dst string) (int64, error) {
 	return io.Copy(out, src)
 }
 
-// ClientIP 返回用户 IP
-func (c *Context) ClientIP() string {
-	ip := c.Request.Header.Get("X-Forwarded-For")
-	if ip = strings.TrimSpace(strings.Split(ip, ",")[0]); ip == "" {
-		if ip = strings.TrimSpace(c.Request.Header.Get("X-Real-Ip")); ip == "" {
-			ip = strings.TrimSpace(c.Request.RemoteAddr)
-			// 存在冒号才使用 SplitHostPort，不然会出错
-			if colon := strings.LastIndex(ip, ":"); colon != -1 {
-				ip, _, _ = net.SplitHostPort(ip)
-			}
-		}
-	}
-	return ip
-}
-
 //  +-----------------------------------------------------------+
 //  | Response                                                  |
 //  +-----------------------------------------------------------+
@@ -517,6 +774,34 @@ func (c *Context) File(filepath string) error {
 	return nil
 }
 
+// FileFS 输出 fs.FS 中指定的文件，文件不存在时返回 404 错误
+//
+// 支持 Range、If-Modified-Since 等条件请求，Last-Modified 使用 fs.FS 中文件的修改时间
+func (c *Context) FileFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(strings.TrimPrefix(path.Clean("/"+name), "/"))
+	if err != nil {
+		return NewHTTPError(http.StatusNotFound)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return NewHTTPError(http.StatusNotFound)
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(b)
+	}
+
+	http.ServeContent(c.Response.Writer, c.Request, info.Name(), info.ModTime(), content)
+	return nil
+}
+
 // Text 将给定的字符串写入响应主体
 func (c *Context) Text(format string, data ...interface{}) (err error) {
 	c.ContentType("text/plain; charset=utf-8")
@@ -547,6 +832,13 @@ func (c *Context) WriteWithStatus(statusCode int, b []byte) (int, error) {
 	return c.Write(b)
 }
 
+// NoContent 发送 HTTP 状态码，不写入响应主体
+func (c *Context) NoContent(statusCode int) error {
+	c.Status(statusCode)
+	c.Response.WriteHeaderNow()
+	return nil
+}
+
 // StreamAttachment 流下载
 func (c *Context) StreamAttachment(r io.Reader, filename string) (err error) {
 	c.Header("content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
@@ -628,18 +920,51 @@ func (c *Context) NoLayout() {
 	c.viewLayout = NoLayout
 }
 
-// View 渲染视图
+// View 渲染视图，内容类型由视图引擎决定，例如 TextEngine 为 text/plain
 func (c *Context) View(name string, optionalData ...map[string]interface{}) error {
 	if c.app.view == nil {
 		return errors.New("view engine is missing, pls use `RegisterView`")
 	}
-	c.ContentType("text/html; charset=utf-8")
-	// 合并视图数据
+	header := c.Response.Header()
+	hasContentType := header.Get("Content-Type") != ""
+	c.ContentType(viewContentType(c.app.viewEngine(name)))
+	err := c.render(&c.Response, name, optionalData...)
+	if err != nil && !hasContentType && !c.Response.Written() {
+		// 渲染失败时没有写入任何数据，由错误处理程序设置内容类型
+		header.Del("Content-Type")
+	}
+	return err
+}
+
+// ViewString 渲染视图并返回字符串，不写入响应
+func (c *Context) ViewString(name string, optionalData ...map[string]interface{}) (string, error) {
+	if c.app.view == nil {
+		return "", errors.New("view engine is missing, pls use `RegisterView`")
+	}
+	buf := new(bytes.Buffer)
+	if err := c.render(buf, name, optionalData...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// render 渲染视图，渲染失败时视图中读取的闪存数据保留到下一个请求
+func (c *Context) render(w io.Writer, name string, optionalData ...map[string]interface{}) error {
+	c.prepareView(optionalData...)
+	consumed := c.flash != nil && c.flash.consumed
+	err := c.app.renderView(w, name, c.viewLayout, c.viewData, c)
+	if err != nil && !consumed && c.flash != nil {
+		c.flash.consumed = false
+	}
+	return err
+}
+
+// prepareView 合并视图数据，视图中的 flashes 和 old 函数在使用时才读取闪存数据
+func (c *Context) prepareView(optionalData ...map[string]interface{}) {
 	if len(optionalData) > 0 {
 		data := optionalData[0]
 		for k, v := range data {
 			c.ViewData(k, v)
 		}
 	}
-	return c.app.view.Render(c.Response.Writer, name, c.viewLayout, c.viewData, c)
 }
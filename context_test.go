@@ -46,6 +46,139 @@ func TestContext_SetGet(t *testing.T) {
 	assert.False(t, ok)
 }
 
+func TestContext_TypedGet(t *testing.T) {
+	c := &Context{}
+	c.Set("name", "foo")
+	c.Set("age", 18)
+	c.Set("tags", []string{"a", "b"})
+
+	name, err := c.GetString("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", name)
+
+	age, err := c.GetInt("age")
+	assert.Nil(t, err)
+	assert.Equal(t, 18, age)
+
+	tags, err := c.GetStringSlice("tags")
+	assert.Nil(t, err)
+	assert.Len(t, tags, 2)
+
+	_, err = c.GetBool("undefined")
+	assert.Equal(t, `potgo: key "undefined" does not exist in context`, err.Error())
+
+	_, err = c.GetString("age")
+	assert.Equal(t, `potgo: value of key "age" is int, not string`, err.Error())
+
+	assert.Equal(t, "foo", c.MustGet("name"))
+	assert.Panics(t, func() {
+		c.MustGet("undefined")
+	})
+}
+
+func TestContext_Copy(t *testing.T) {
+	r := New()
+	var cp *Context
+	r.GET("/user/{id}", func(c *Context) error {
+		c.Set("foo", "bar")
+		if cp == nil {
+			cp = c.Copy()
+		}
+		c.Set("foo", "baz")
+		return nil
+	}).Name("user")
+
+	req, _ := http.NewRequest("GET", "/user/10", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 原上下文被复用后副本不受影响
+	req, _ = http.NewRequest("GET", "/user/20", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, cp.IsCopy())
+	assert.Equal(t, "10", cp.Param("id"))
+	value, ok := cp.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", value)
+	assert.Equal(t, "user", cp.Route().GetName())
+	assert.Equal(t, "/user/:id", cp.Route().Path())
+	assert.Equal(t, "/user/10", cp.Request.URL.Path)
+
+	_, err := cp.Write([]byte("foo"))
+	assert.NotNil(t, err)
+}
+
+func TestContext_Fork(t *testing.T) {
+	r := New()
+	res := httptest.NewRecorder()
+	r.Use(func(c *Context) error {
+		c.Set("foo", "bar")
+		f := c.Fork(res)
+		defer f.Release()
+		return f.Next()
+	})
+	r.GET("/user/{id}", func(c *Context) error {
+		foo, _ := c.GetString("foo")
+		return c.Text("%s %s", c.Param("id"), foo)
+	})
+
+	req, _ := http.NewRequest("GET", "/user/10", nil)
+	orig := httptest.NewRecorder()
+	r.ServeHTTP(orig, req)
+
+	assert.Equal(t, "10 bar", res.Body.String())
+	assert.Equal(t, "", orig.Body.String())
+}
+
+func TestContext_UseAfterRelease(t *testing.T) {
+	r := New()
+	r.Debug(true)
+	var leaked *Context
+	r.GET("/test", func(c *Context) error {
+		leaked = c
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.PanicsWithValue(t, errContextReleased, func() {
+		leaked.Param("id")
+	})
+	assert.PanicsWithValue(t, errContextReleased, func() {
+		_, _ = leaked.Write([]byte("foo"))
+	})
+}
+
+func TestContext_ReleaseFork(t *testing.T) {
+	r := New()
+	r.Debug(true)
+	var parent, fork *Context
+	r.GET("/test", func(c *Context) error {
+		parent = c
+		fork = c.Fork(httptest.NewRecorder())
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Fork 返回的上下文结束之前，原上下文不会被释放
+	assert.NotPanics(t, func() {
+		parent.Param("id")
+		fork.Set("foo", "bar")
+	})
+
+	fork.Release()
+	assert.PanicsWithValue(t, errContextReleased, func() {
+		parent.Param("id")
+	})
+	assert.PanicsWithValue(t, errContextReleased, func() {
+		_, _ = fork.Write([]byte("foo"))
+	})
+	assert.NotPanics(t, fork.Release)
+}
+
 func getNextHandler(tag string) HandlerFunc {
 	return func(c *Context) error {
 		_, _ = fmt.Fprintf(c.Response.Writer, "<%v>", tag)
@@ -200,6 +333,12 @@ func TestContextClientIP(t *testing.T) {
 	c := &Context{}
 	c.reset(httptest.NewRecorder(), req)
 
+	// 没有设置受信任的代理时忽略转发的请求头
+	assert.Equal(t, "192.168.100.3", c.ClientIP())
+
+	c.app = New()
+	assert.Nil(t, c.app.SetTrustedProxies("192.168.100.3"))
+
 	assert.Equal(t, "192.168.100.1", c.ClientIP())
 	req.Header.Del("X-Forwarded-For")
 	assert.Equal(t, "192.168.100.2", c.ClientIP())
@@ -278,3 +417,25 @@ func TestContext_RouteRedirect(t *testing.T) {
 	err = c.RouteRedirect("user", "id", 10, "302")
 	assert.Equal(t, "invalid redirect status code", err.Error())
 }
+
+func TestContext_ViewString(t *testing.T) {
+	r := New()
+	view := HTML("./testdata/views_1", ".html")
+	view.Layout("layout.html")
+	_ = r.RegisterView(view)
+
+	r.GET("/hello", func(c *Context) error {
+		c.ViewData("name", "world")
+		s, err := c.ViewString("hello.html")
+		if err != nil {
+			return err
+		}
+		return c.Text("%d:%s", len(s), s)
+	})
+
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, `47:<main id="Content"><h1>Hello, world</h1></main>`, res.Body.String())
+}
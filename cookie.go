@@ -0,0 +1,247 @@
+package potgo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCookie cookie 的值被篡改或者无法解码
+	ErrInvalidCookie = errors.New("potgo: invalid cookie value")
+	// ErrCookieExpired cookie 已过期
+	ErrCookieExpired = errors.New("potgo: cookie has expired")
+
+	errSecretKeysMissing = errors.New("potgo: secret keys are missing, pls use `SetSecretKeys`")
+	errHashKeyMissing    = errors.New("potgo: hash key must not be empty")
+	errBlockKeyMissing   = errors.New("potgo: block key is missing, cannot encrypt")
+	errInvalidBlockKey   = errors.New("potgo: block key must be 16, 24 or 32 bytes")
+)
+
+// newSecretKey 由密钥派生出签名密钥和 AES-256 加密密钥
+func newSecretKey(key []byte) *CookieCodec {
+	codec, _ := NewCookieCodec(deriveKey(key, "potgo.cookie.signing"), deriveKey(key, "potgo.cookie.encryption"))
+	return codec
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// SetSecretKeys 设置签名和加密 cookie 使用的密钥
+//
+// 使用第一个密钥签名和加密，验证和解密时依次尝试所有密钥，
+// 所以轮换密钥时把新密钥放在第一位，旧密钥放在后面，待旧 cookie 全部过期后再删除旧密钥。
+// 密钥不能为空，否则 panic
+func (app *Application) SetSecretKeys(keys ...[]byte) {
+	app.secretKeys = make([]*CookieCodec, 0, len(keys))
+	for _, key := range keys {
+		if len(key) == 0 {
+			panic("potgo: secret key must not be empty")
+		}
+		app.secretKeys = append(app.secretKeys, newSecretKey(key))
+	}
+}
+
+// CookieOptions 签名和加密 cookie 的选项
+type CookieOptions struct {
+	// ScriptAccess 为 true 时不默认设置 HttpOnly，允许在 JavaScript 中读取 cookie
+	ScriptAccess bool
+}
+
+// SetSignedCookie 设置使用 HMAC-SHA256 签名的 cookie，cookie 的值对客户端可见但不能被篡改
+//
+// 过期时间会写入签名的值中，默认设置 HttpOnly，SameSite 默认为 Lax，HTTPS 请求默认设置 Secure，
+// 需要在 JavaScript 中读取的 cookie 使用 CookieOptions{ScriptAccess: true}
+func (c *Context) SetSignedCookie(cookie *http.Cookie, options ...CookieOptions) error {
+	return c.setSecureCookie(cookie, false, options)
+}
+
+// GetSignedCookie 获取并验证签名的 cookie
+func (c *Context) GetSignedCookie(name string) (string, error) {
+	return c.getSecureCookie(name, false)
+}
+
+// SetEncryptedCookie 设置使用 AES-GCM 加密的 cookie，cookie 的值对客户端不可见并且不能被篡改
+//
+// 过期时间会写入加密的值中，HttpOnly、SameSite 和 Secure 的默认值与 SetSignedCookie 相同
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie, options ...CookieOptions) error {
+	return c.setSecureCookie(cookie, true, options)
+}
+
+// GetEncryptedCookie 获取并解密加密的 cookie
+func (c *Context) GetEncryptedCookie(name string) (string, error) {
+	return c.getSecureCookie(name, true)
+}
+
+func (c *Context) setSecureCookie(cookie *http.Cookie, encrypt bool, options []CookieOptions) error {
+	if len(c.app.secretKeys) == 0 {
+		return errSecretKeysMissing
+	}
+
+	var expires int64
+	if cookie.MaxAge > 0 {
+		expires = time.Now().Add(time.Duration(cookie.MaxAge) * time.Second).Unix()
+	} else if !cookie.Expires.IsZero() {
+		expires = cookie.Expires.Unix()
+	}
+
+	value, err := c.app.secretKeys[0].Encode(cookie.Name, []byte(cookie.Value), expires, encrypt)
+	if err != nil {
+		return err
+	}
+
+	sc := *cookie
+	sc.Value = value
+	if len(options) == 0 || !options[0].ScriptAccess {
+		sc.HttpOnly = true
+	}
+	if sc.SameSite == 0 { // 未设置
+		sc.SameSite = http.SameSiteLaxMode
+	}
+	if c.Request != nil && c.Scheme() == "https" {
+		sc.Secure = true
+	}
+	c.SetCookie(&sc)
+	return nil
+}
+
+func (c *Context) getSecureCookie(name string, encrypt bool) (string, error) {
+	if len(c.app.secretKeys) == 0 {
+		return "", errSecretKeysMissing
+	}
+
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, codec := range c.app.secretKeys {
+		value, err := codec.Decode(name, cookie.Value, encrypt)
+		if err != ErrInvalidCookie {
+			return string(value), err
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// CookieCodec 签名和加密 cookie 的值，签名使用 HMAC-SHA256，加密使用 AES-GCM
+//
+// 编码格式为 base64(过期时间|base64(数据)|base64(签名))，签名包含 cookie 名称和是否加密，
+// 防止将一个 cookie 的值用于另一个 cookie
+type CookieCodec struct {
+	hashKey []byte
+	aead    cipher.AEAD
+}
+
+// NewCookieCodec 创建 CookieCodec，hashKey 为签名密钥，blockKey 为加密密钥
+//
+// blockKey 为 nil 时只能签名，否则长度必须为 16、24 或 32 字节，分别对应 AES-128、AES-192 和 AES-256
+func NewCookieCodec(hashKey, blockKey []byte) (*CookieCodec, error) {
+	if len(hashKey) == 0 {
+		return nil, errHashKeyMissing
+	}
+	codec := &CookieCodec{hashKey: hashKey}
+	if blockKey != nil {
+		block, err := aes.NewCipher(blockKey)
+		if err != nil {
+			return nil, errInvalidBlockKey
+		}
+		if codec.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return codec, nil
+}
+
+// CanEncrypt 是否设置了加密密钥
+func (codec *CookieCodec) CanEncrypt() bool {
+	return codec.aead != nil
+}
+
+// Encode 编码名称为 name 的 cookie 的值，expires 为过期时间的 Unix 时间戳，0 表示不过期，
+// encrypt 为 true 时加密，否则只签名
+func (codec *CookieCodec) Encode(name string, value []byte, expires int64, encrypt bool) (string, error) {
+	data := value
+	if encrypt {
+		if codec.aead == nil {
+			return "", errBlockKeyMissing
+		}
+		nonce := make([]byte, codec.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		data = codec.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+
+	msg := strconv.FormatInt(expires, 10) + "|" + base64.RawURLEncoding.EncodeToString(data)
+	mac := codec.sign(name, msg, encrypt)
+	return base64.RawURLEncoding.EncodeToString([]byte(msg + "|" + base64.RawURLEncoding.EncodeToString(mac))), nil
+}
+
+// Decode 验证并解码名称为 name 的 cookie 的值，值被篡改时返回 ErrInvalidCookie，已过期时返回 ErrCookieExpired
+func (codec *CookieCodec) Decode(name string, value string, encrypt bool) ([]byte, error) {
+	if encrypt && codec.aead == nil {
+		return nil, errBlockKeyMissing
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+
+	parts := strings.SplitN(string(b), "|", 3)
+	if len(parts) != 3 {
+		return nil, ErrInvalidCookie
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, codec.sign(name, parts[0]+"|"+parts[1], encrypt)) {
+		return nil, ErrInvalidCookie
+	}
+
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	if expires > 0 && time.Now().Unix() > expires {
+		return nil, ErrCookieExpired
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+
+	if encrypt {
+		size := codec.aead.NonceSize()
+		if len(data) < size {
+			return nil, ErrInvalidCookie
+		}
+		if data, err = codec.aead.Open(nil, data[:size], data[size:], []byte(name)); err != nil {
+			return nil, ErrInvalidCookie
+		}
+	}
+	return data, nil
+}
+
+// sign 签名包含 cookie 名称和是否加密
+func (codec *CookieCodec) sign(name, msg string, encrypt bool) []byte {
+	mode := "s"
+	if encrypt {
+		mode = "e"
+	}
+	h := hmac.New(sha256.New, codec.hashKey)
+	h.Write([]byte(mode + "|" + name + "|" + msg))
+	return h.Sum(nil)
+}
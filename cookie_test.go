@@ -0,0 +1,141 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCookieTestContext(app *Application, cookies ...*http.Cookie) (*Context, *httptest.ResponseRecorder) {
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	c := &Context{app: app}
+	c.reset(res, req)
+	return c, res
+}
+
+func TestContext_SignedCookie(t *testing.T) {
+	app := New()
+	app.SetSecretKeys([]byte("secret"))
+
+	c, res := newCookieTestContext(app)
+	err := c.SetSignedCookie(&http.Cookie{Name: "foo", Value: "bar", MaxAge: 60})
+	assert.Nil(t, err)
+
+	cookie := res.Result().Cookies()[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	// 需要在 JavaScript 中读取的 cookie 不设置 HttpOnly
+	c, res = newCookieTestContext(app)
+	_ = c.SetSignedCookie(&http.Cookie{Name: "theme", Value: "dark"}, CookieOptions{ScriptAccess: true})
+	assert.False(t, res.Result().Cookies()[0].HttpOnly)
+
+	c, _ = newCookieTestContext(app, cookie)
+	value, err := c.GetSignedCookie("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", value)
+
+	// 篡改
+	c, _ = newCookieTestContext(app, &http.Cookie{Name: "foo", Value: cookie.Value + "x"})
+	_, err = c.GetSignedCookie("foo")
+	assert.Equal(t, ErrInvalidCookie, err)
+
+	// 不能用于其它名称的 cookie
+	c, _ = newCookieTestContext(app, &http.Cookie{Name: "baz", Value: cookie.Value})
+	_, err = c.GetSignedCookie("baz")
+	assert.Equal(t, ErrInvalidCookie, err)
+
+	c, _ = newCookieTestContext(app)
+	_, err = c.GetSignedCookie("foo")
+	assert.Equal(t, http.ErrNoCookie, err)
+}
+
+func TestContext_EncryptedCookie(t *testing.T) {
+	app := New()
+	app.SetSecretKeys([]byte("secret"))
+
+	c, res := newCookieTestContext(app)
+	err := c.SetEncryptedCookie(&http.Cookie{Name: "foo", Value: "bar"})
+	assert.Nil(t, err)
+
+	cookie := res.Result().Cookies()[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.NotContains(t, cookie.Value, "bar")
+
+	c, _ = newCookieTestContext(app, cookie)
+	value, err := c.GetEncryptedCookie("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", value)
+
+	_, err = c.GetSignedCookie("foo")
+	assert.NotNil(t, err)
+}
+
+func TestContext_SecureCookieExpired(t *testing.T) {
+	app := New()
+	app.SetSecretKeys([]byte("secret"))
+
+	value, _ := app.secretKeys[0].Encode("foo", []byte("bar"), 1, false)
+	c, _ := newCookieTestContext(app, &http.Cookie{Name: "foo", Value: value})
+	_, err := c.GetSignedCookie("foo")
+	assert.Equal(t, ErrCookieExpired, err)
+}
+
+func TestApplication_SetSecretKeys(t *testing.T) {
+	app := New()
+	c, _ := newCookieTestContext(app)
+	assert.Equal(t, errSecretKeysMissing, c.SetSignedCookie(&http.Cookie{Name: "foo", Value: "bar"}))
+
+	// 不能使用空的密钥
+	assert.Panics(t, func() {
+		app.SetSecretKeys([]byte{})
+	})
+	assert.Panics(t, func() {
+		app.SetSecretKeys([]byte("new"), nil)
+	})
+
+	app.SetSecretKeys([]byte("old"))
+	c, res := newCookieTestContext(app)
+	_ = c.SetEncryptedCookie(&http.Cookie{Name: "foo", Value: "bar"})
+	cookie := res.Result().Cookies()[0]
+
+	// 密钥轮换
+	app.SetSecretKeys([]byte("new"), []byte("old"))
+	c, _ = newCookieTestContext(app, cookie)
+	value, err := c.GetEncryptedCookie("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", value)
+
+	app.SetSecretKeys([]byte("new"))
+	c, _ = newCookieTestContext(app, cookie)
+	_, err = c.GetEncryptedCookie("foo")
+	assert.Equal(t, ErrInvalidCookie, err)
+}
+
+func TestCookieCodec(t *testing.T) {
+	_, err := NewCookieCodec(nil, nil)
+	assert.Equal(t, errHashKeyMissing, err)
+	_, err = NewCookieCodec([]byte("hash-key"), []byte("short"))
+	assert.Equal(t, errInvalidBlockKey, err)
+
+	signer, err := NewCookieCodec([]byte("hash-key"), nil)
+	assert.Nil(t, err)
+	assert.False(t, signer.CanEncrypt())
+	_, err = signer.Encode("foo", []byte("bar"), 0, true)
+	assert.Equal(t, errBlockKeyMissing, err)
+
+	codec, _ := NewCookieCodec([]byte("hash-key"), []byte("0123456789abcdef"))
+	value, err := codec.Encode("foo", []byte("bar"), 0, true)
+	assert.Nil(t, err)
+	b, err := codec.Decode("foo", value, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", string(b))
+
+	_, err = codec.Decode("baz", value, true)
+	assert.Equal(t, ErrInvalidCookie, err)
+}
@@ -0,0 +1,13 @@
+package potgo
+
+// CSPNonceKey Secure 中间件在上下文中保存 CSP nonce 使用的键
+var CSPNonceKey = NewKey("potgo.csp.nonce", "")
+
+// CSPNonce 返回当前请求的 CSP nonce，没有使用 Secure 中间件或者 CSP 中没有 nonce 时返回空字符串
+//
+//	<script nonce="{{ cspNonce }}">...</script>
+func (c *Context) CSPNonce() string {
+	nonce, _ := CSPNonceKey.Get(c)
+	s, _ := nonce.(string)
+	return s
+}
@@ -0,0 +1,31 @@
+package potgo
+
+import (
+	"html/template"
+)
+
+var (
+	// CSRFTokenKey CSRF 中间件在上下文中保存令牌使用的键
+	CSRFTokenKey = NewKey("potgo.csrf.token", "")
+	// CSRFFieldKey CSRF 中间件在上下文中保存表单字段名称使用的键
+	CSRFFieldKey = NewKey("potgo.csrf.field", "")
+)
+
+// CSRFToken 返回当前请求的 CSRF 令牌，没有使用 CSRF 中间件时返回空字符串
+func (c *Context) CSRFToken() string {
+	token, _ := CSRFTokenKey.Get(c)
+	s, _ := token.(string)
+	return s
+}
+
+// CSRFField 返回包含 CSRF 令牌的隐藏表单字段
+func (c *Context) CSRFField() template.HTML {
+	token := c.CSRFToken()
+	if token == "" {
+		return ""
+	}
+	field, _ := CSRFFieldKey.Get(c)
+	name, _ := field.(string)
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(name) +
+		`" value="` + template.HTMLEscapeString(token) + `">`)
+}
@@ -1,6 +1,15 @@
 package potgo
 
-import "net/http"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	errContextCopied   = errors.New("potgo: cannot write response with a copied Context")
+	errContextReleased = errors.New("potgo: Context used after the request completed, use Context.Copy() when passing it to goroutines")
+)
 
 // HTTPError HTTP 错误接口
 type HTTPError interface {
@@ -12,13 +21,17 @@ type HTTPError interface {
 type httpError struct {
 	Code    int    `json:"status" xml:"status"`
 	Message string `json:"message" xml:"message"`
+	custom  bool   // 是否指定了错误信息，没有指定时使用翻译后的默认信息
 }
 
 // NewHTTPError 创建 HttpError 实例
+//
+// 没有指定错误信息时使用 http.StatusText，使用 i18n 中间件时错误处理程序收到的是翻译后的信息
 func NewHTTPError(status int, message ...string) HTTPError {
-	e := &httpError{status, http.StatusText(status)}
+	e := &httpError{Code: status, Message: http.StatusText(status)}
 	if len(message) > 0 {
 		e.Message = message[0]
+		e.custom = true
 	}
 	return e
 }
@@ -32,3 +45,18 @@ func (e *httpError) Error() string {
 func (e *httpError) Status() int {
 	return e.Code
 }
+
+// KeyError 上下文中的数据不存在或者类型不匹配
+type KeyError struct {
+	Key    string // 数据的键
+	Type   string // 期望的类型，为空表示数据不存在
+	Actual string // 实际的类型
+}
+
+// Error 返回错误信息
+func (e *KeyError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("potgo: key %q does not exist in context", e.Key)
+	}
+	return fmt.Sprintf("potgo: value of key %q is %s, not %s", e.Key, e.Actual, e.Type)
+}
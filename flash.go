@@ -0,0 +1,281 @@
+package potgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// flashCookieName 保存闪存消息的 cookie 名称
+	flashCookieName = "potgo_flash"
+	// maxFlashCookieSize 闪存 cookie 名称和值的最大长度，浏览器会丢弃超过 4KB 的 cookie
+	maxFlashCookieSize = 4096
+)
+
+// FlashMessage 闪存消息
+type FlashMessage struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// flashData 保存在 cookie 中的闪存数据
+type flashData struct {
+	Messages []FlashMessage      `json:"m,omitempty"`
+	Input    map[string][]string `json:"i,omitempty"`
+}
+
+func (d *flashData) empty() bool {
+	return len(d.Messages) == 0 && len(d.Input) == 0
+}
+
+// flash 当前请求的闪存状态
+type flash struct {
+	in       flashData // 上一个请求写入的闪存数据
+	out      flashData // 当前请求写入的闪存数据
+	loaded   bool
+	existed  bool
+	consumed bool // 是否读取过上一个请求的闪存数据，读取过才会在响应中删除
+	hooked   bool
+}
+
+// Flash 添加闪存消息，闪存消息保存在 cookie 中，可以在下一个请求中读取，例如重定向后显示操作结果
+//
+//	c.Flash("success", "保存成功")
+//	return c.RouteRedirect("posts")
+//
+// 闪存 cookie 使用 SetSecretKeys 设置的密钥签名，没有设置密钥时 panic，读取时忽略请求中的闪存 cookie
+func (c *Context) Flash(kind, message string) {
+	c.mustFlashKeys()
+	f := c.getFlash()
+	f.out.Messages = append(f.out.Messages, FlashMessage{Kind: kind, Message: message})
+	c.hookFlash()
+}
+
+// Flashes 读取上一个请求添加的闪存消息，kind 不为空时只返回指定类型的消息
+//
+// 读取闪存数据后，包括在视图中使用 flashes 和 old 函数，上一个请求添加的闪存消息和旧输入都会被删除
+func (c *Context) Flashes(kind ...string) []FlashMessage {
+	f := c.consumeFlash()
+	if len(kind) == 0 {
+		return f.in.Messages
+	}
+
+	messages := make([]FlashMessage, 0)
+	for _, m := range f.in.Messages {
+		if m.Kind == kind[0] {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// FlashInput 将当前请求的表单数据保存为旧输入，在下一个请求中使用 OldInput 读取，
+// 例如表单验证失败后重定向回表单页面时回填表单。
+// 指定 fields 时只保存指定的字段，否则保存除了名称包含 password 以外的所有字段。
+// 闪存 cookie 超过 4KB 时不保存旧输入，没有设置密钥时 panic
+func (c *Context) FlashInput(fields ...string) {
+	c.mustFlashKeys()
+	f := c.getFlash()
+	if f.out.Input == nil {
+		f.out.Input = make(map[string][]string)
+	}
+
+	form := c.getForm()
+	if len(fields) > 0 {
+		for _, field := range fields {
+			if vs, ok := form[field]; ok {
+				f.out.Input[field] = vs
+			}
+		}
+	} else {
+		for field, vs := range form {
+			if !strings.Contains(strings.ToLower(field), "password") {
+				f.out.Input[field] = vs
+			}
+		}
+	}
+	c.hookFlash()
+}
+
+// OldInput 读取上一个请求使用 FlashInput 保存的字段值
+func (c *Context) OldInput(field string, defaultValue ...string) string {
+	f := c.consumeFlash()
+	if vs := f.in.Input[field]; len(vs) > 0 {
+		return vs[0]
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// mustFlashKeys 没有设置密钥时 panic，避免闪存数据静默丢失
+func (c *Context) mustFlashKeys() {
+	if c.app == nil || len(c.app.secretKeys) == 0 {
+		panic(errSecretKeysMissing)
+	}
+}
+
+func (c *Context) getFlash() *flash {
+	if c.flash == nil {
+		c.flash = &flash{}
+	}
+	return c.flash
+}
+
+// loadFlash 读取上一个请求写入的闪存数据
+func (c *Context) loadFlash() *flash {
+	f := c.getFlash()
+	if f.loaded {
+		return f
+	}
+	f.loaded = true
+
+	if c.Request == nil {
+		return f
+	}
+	cookie, err := c.Request.Cookie(flashCookieName)
+	if err != nil {
+		return f
+	}
+	f.existed = true
+
+	if value, err := c.decodeFlash(cookie.Value); err == nil {
+		_ = json.Unmarshal(value, &f.in)
+	}
+	return f
+}
+
+// consumeFlash 读取上一个请求写入的闪存数据，并在响应中删除
+func (c *Context) consumeFlash() *flash {
+	f := c.loadFlash()
+	if !f.consumed {
+		f.consumed = true
+		if f.existed {
+			c.hookFlash()
+		}
+	}
+	return f
+}
+
+// mergeFlash 合并 Fork 返回的上下文中的闪存数据，f 已经写入响应时闪存数据已经保存在 f 的响应头中
+func (c *Context) mergeFlash(f *Context) {
+	ff := f.flash
+	if ff == nil || f.Response.Written() {
+		return
+	}
+	f.flash = nil
+
+	cf := c.getFlash()
+	cf.out.Messages = append(cf.out.Messages, ff.out.Messages...)
+	if len(ff.out.Input) > 0 {
+		if cf.out.Input == nil {
+			cf.out.Input = make(map[string][]string, len(ff.out.Input))
+		}
+		for field, vs := range ff.out.Input {
+			cf.out.Input[field] = vs
+		}
+	}
+	if ff.loaded && !cf.loaded {
+		cf.in, cf.loaded, cf.existed = ff.in, true, ff.existed
+	}
+	if ff.consumed && cf.loaded {
+		cf.consumed = true
+	}
+	if ff.hooked {
+		c.hookFlash()
+	}
+}
+
+// hookFlash 在发送响应头之前写入闪存数据
+func (c *Context) hookFlash() {
+	f := c.getFlash()
+	if f.hooked {
+		return
+	}
+	f.hooked = true
+	c.Response.Before(c.saveFlash)
+}
+
+func (c *Context) saveFlash() {
+	f := c.flash
+	if f == nil {
+		return
+	}
+
+	// 没有读取上一个请求的闪存数据时，保留到下一个请求
+	next := f.out
+	if !f.consumed {
+		if next.empty() {
+			return
+		}
+		in := c.loadFlash().in
+		next.Messages = append(append([]FlashMessage{}, in.Messages...), next.Messages...)
+		if next.Input == nil {
+			next.Input = in.Input
+		}
+	}
+
+	if next.empty() {
+		if f.existed {
+			c.SetCookie(&http.Cookie{Name: flashCookieName, MaxAge: -1, HttpOnly: true})
+		}
+		return
+	}
+
+	value, ok := c.encodeFlashData(next)
+	if !ok && len(next.Input) > 0 {
+		// 超过 cookie 的大小限制时丢弃旧输入，只保留闪存消息
+		next.Input = nil
+		value, ok = c.encodeFlashData(next)
+	}
+	// 仍然超过大小限制时丢弃最早的闪存消息
+	for !ok && len(next.Messages) > 1 {
+		next.Messages = next.Messages[1:]
+		value, ok = c.encodeFlashData(next)
+	}
+	if !ok {
+		return
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     flashCookieName,
+		Value:    value,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// encodeFlashData 编码并签名闪存数据，失败或者超过 cookie 的大小限制时返回 false
+func (c *Context) encodeFlashData(data flashData) (string, bool) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", false
+	}
+	value, err := c.encodeFlash(b)
+	if err != nil || len(flashCookieName)+1+len(value) > maxFlashCookieSize {
+		return "", false
+	}
+	return value, true
+}
+
+// encodeFlash 使用应用的密钥对闪存数据签名，没有设置密钥时返回错误
+func (c *Context) encodeFlash(b []byte) (string, error) {
+	if c.app == nil || len(c.app.secretKeys) == 0 {
+		return "", errSecretKeysMissing
+	}
+	return c.app.secretKeys[0].Encode(flashCookieName, b, 0, false)
+}
+
+// decodeFlash 验证闪存数据的签名，没有设置密钥时不信任任何闪存 cookie
+func (c *Context) decodeFlash(value string) ([]byte, error) {
+	if c.app == nil || len(c.app.secretKeys) == 0 {
+		return nil, errSecretKeysMissing
+	}
+	for _, codec := range c.app.secretKeys {
+		if b, err := codec.Decode(flashCookieName, value, false); err == nil {
+			return b, nil
+		}
+	}
+	return nil, ErrInvalidCookie
+}
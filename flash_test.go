@@ -0,0 +1,181 @@
+package potgo
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func getFlashCookie(res *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == flashCookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestContext_Flash(t *testing.T) {
+	r := New()
+	r.SetSecretKeys([]byte("secret"))
+	_ = r.RegisterView(HTML("./testdata/views_3", ".html"))
+
+	r.POST("/save", func(c *Context) error {
+		c.Flash("error", "invalid email")
+		c.FlashInput()
+		return c.Redirect("/form")
+	})
+	r.GET("/form", func(c *Context) error {
+		return c.View("form.html")
+	})
+	r.GET("/redirect", func(c *Context) error {
+		return c.Redirect("/form")
+	})
+	r.GET("/mail", func(c *Context) error {
+		body, err := c.ViewString("csp.html")
+		if err != nil {
+			return err
+		}
+		return c.Text(body)
+	})
+	r.GET("/broken", func(c *Context) error {
+		return c.View("broken.html", Map{"name": "foo"})
+	})
+
+	req, _ := http.NewRequest("POST", "/save", strings.NewReader("name=foo&password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusFound, res.Code)
+	cookie := getFlashCookie(res)
+	assert.NotNil(t, cookie)
+
+	// 没有读取闪存数据的请求不会删除闪存数据
+	req, _ = http.NewRequest("GET", "/redirect", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Nil(t, getFlashCookie(res))
+
+	// 渲染不使用闪存数据的视图也不会删除闪存数据
+	req, _ = http.NewRequest("GET", "/mail", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Nil(t, getFlashCookie(res))
+
+	// 渲染失败时不删除闪存数据
+	req, _ = http.NewRequest("GET", "/broken", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Nil(t, getFlashCookie(res))
+
+	req, _ = http.NewRequest("GET", "/form", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "error:invalid email;name=foo", res.Body.String())
+	assert.Equal(t, -1, getFlashCookie(res).MaxAge)
+
+	req, _ = http.NewRequest("GET", "/form", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "name=", res.Body.String())
+}
+
+func TestContext_FlashesKind(t *testing.T) {
+	r := New()
+	r.SetSecretKeys([]byte("secret"))
+	r.GET("/set", func(c *Context) error {
+		c.Flash("success", "saved")
+		c.Flash("info", "hello")
+		return nil
+	})
+	r.GET("/get", func(c *Context) error {
+		var messages []string
+		for _, m := range c.Flashes("info") {
+			messages = append(messages, m.Message)
+		}
+		return c.Text("%v %s", messages, c.OldInput("password", "none"))
+	})
+
+	req, _ := http.NewRequest("GET", "/set", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	cookie := getFlashCookie(res)
+	assert.NotNil(t, cookie)
+
+	req, _ = http.NewRequest("GET", "/get", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "[hello] none", res.Body.String())
+
+	// 签名被篡改时忽略闪存数据
+	req, _ = http.NewRequest("GET", "/get", nil)
+	req.AddCookie(&http.Cookie{Name: flashCookieName, Value: cookie.Value + "x"})
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "[] none", res.Body.String())
+}
+
+func TestContext_FlashWithoutSecretKeys(t *testing.T) {
+	r := New()
+	r.POST("/save", func(c *Context) error {
+		c.Flash("error", "invalid email")
+		return c.Redirect("/form")
+	})
+	r.GET("/form", func(c *Context) error {
+		return c.Text("%d", len(c.Flashes()))
+	})
+
+	// 没有设置密钥时 panic，不会静默丢弃闪存数据
+	req, _ := http.NewRequest("POST", "/save", nil)
+	res := httptest.NewRecorder()
+	assert.PanicsWithValue(t, errSecretKeysMissing, func() {
+		r.ServeHTTP(res, req)
+	})
+	assert.Nil(t, getFlashCookie(res))
+
+	// 也不读取客户端伪造的闪存 cookie
+	req, _ = http.NewRequest("GET", "/form", nil)
+	req.AddCookie(&http.Cookie{Name: flashCookieName, Value: base64.RawURLEncoding.EncodeToString([]byte(`{"m":[{"kind":"success","message":"forged"}]}`))})
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "0", res.Body.String())
+}
+
+func TestContext_FlashCookieSize(t *testing.T) {
+	r := New()
+	r.SetSecretKeys([]byte("secret"))
+	r.POST("/save", func(c *Context) error {
+		c.Flash("error", "invalid content")
+		c.FlashInput()
+		return c.Redirect("/form")
+	})
+	r.GET("/form", func(c *Context) error {
+		return c.Text("%d %s", len(c.Flashes("error")), c.OldInput("content", "none"))
+	})
+
+	// 超过 4KB 时丢弃旧输入，保留闪存消息
+	body := "content=" + strings.Repeat("a", 5000)
+	req, _ := http.NewRequest("POST", "/save", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	cookie := getFlashCookie(res)
+	assert.NotNil(t, cookie)
+	assert.True(t, len(cookie.Name)+1+len(cookie.Value) <= maxFlashCookieSize)
+
+	req, _ = http.NewRequest("GET", "/form", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, "1 none", res.Body.String())
+}
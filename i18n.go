@@ -0,0 +1,50 @@
+package potgo
+
+import "strconv"
+
+// TranslatorKey i18n 中间件在上下文中保存翻译器使用的键
+var TranslatorKey = NewKey("potgo.translator")
+
+// Translator 翻译接口，由 i18n 包实现
+type Translator interface {
+	// Locale 返回当前请求的语言，例如 zh-CN
+	Locale() string
+	// Translate 翻译消息，args 为 "名称, 值" 形式的占位符参数，消息不存在时 ok 为 false
+	Translate(key string, args ...interface{}) (message string, ok bool)
+}
+
+// T 翻译消息，没有使用 i18n 中间件或者消息不存在时返回 key
+//
+//	c.T("cart.items", "count", 3)
+func (c *Context) T(key string, args ...interface{}) string {
+	if translator := c.translator(); translator != nil {
+		if message, ok := translator.Translate(key, args...); ok {
+			return message
+		}
+	}
+	return key
+}
+
+// Locale 返回当前请求的语言，没有使用 i18n 中间件时返回空字符串
+func (c *Context) Locale() string {
+	if translator := c.translator(); translator != nil {
+		return translator.Locale()
+	}
+	return ""
+}
+
+func (c *Context) translator() Translator {
+	value, _ := TranslatorKey.Get(c)
+	translator, _ := value.(Translator)
+	return translator
+}
+
+// statusText 返回翻译后的 HTTP 状态信息，消息的键为 http.状态码，例如 http.404
+func (c *Context) statusText(code int, defaultText string) string {
+	if translator := c.translator(); translator != nil {
+		if message, ok := translator.Translate("http." + strconv.Itoa(code)); ok {
+			return message
+		}
+	}
+	return defaultText
+}
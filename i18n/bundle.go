@@ -0,0 +1,364 @@
+// Package i18n 国际化，加载消息文件并根据请求选择语言
+package i18n
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/icodechef/potgo"
+)
+
+// Extension 消息文件的扩展名，文件名为语言，例如 zh-CN.lang、en.lang
+const Extension = ".lang"
+
+// Bundle 所有语言的消息
+type Bundle struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	catalogs      map[string]map[string]string
+	rules         map[string]PluralRule
+}
+
+// NewBundle 创建 Bundle，defaultLocale 为默认语言，找不到消息时使用默认语言的消息
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{
+		defaultLocale: Canonical(defaultLocale),
+		catalogs:      make(map[string]map[string]string),
+		rules:         make(map[string]PluralRule),
+	}
+}
+
+// DefaultLocale 返回默认语言
+func (b *Bundle) DefaultLocale() string {
+	return b.defaultLocale
+}
+
+// LoadDir 加载目录下所有扩展名为 .lang 的消息文件
+func (b *Bundle) LoadDir(dir string) error {
+	return b.LoadFS(os.DirFS(dir))
+}
+
+// LoadFS 加载 fs.FS 中所有扩展名为 .lang 的消息文件，例如 embed.FS
+func (b *Bundle) LoadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != Extension {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err = b.Parse(strings.TrimSuffix(path.Base(name), Extension), data); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Parse 解析消息文件的内容并添加到 locale 语言中
+func (b *Bundle) Parse(locale string, data []byte) error {
+	messages, err := parseCatalog(data)
+	if err != nil {
+		return err
+	}
+	b.AddMessages(locale, messages)
+	return nil
+}
+
+// AddMessages 添加消息，已经存在的消息会被覆盖
+func (b *Bundle) AddMessages(locale string, messages map[string]string) {
+	locale = Canonical(locale)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	catalog, ok := b.catalogs[locale]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		b.catalogs[locale] = catalog
+	}
+	for key, message := range messages {
+		catalog[key] = message
+	}
+}
+
+// SetPluralRule 设置语言的复数规则，lang 为不包含地区的语言，例如 en
+func (b *Bundle) SetPluralRule(lang string, rule PluralRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rules[strings.ToLower(lang)] = rule
+}
+
+// Locales 返回支持的语言，包括默认语言
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	locales := make([]string, 0, len(b.catalogs)+1)
+	if _, ok := b.catalogs[b.defaultLocale]; !ok && b.defaultLocale != "" {
+		locales = append(locales, b.defaultLocale)
+	}
+	for locale := range b.catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match 按顺序返回第一个支持的语言，语言不完全相同时匹配相同的基础语言，例如 zh-TW 匹配 zh、zh-CN，
+// 没有支持的语言时返回空字符串
+func (b *Bundle) Match(locales ...string) string {
+	supported := b.Locales()
+	for _, locale := range locales {
+		locale = Canonical(locale)
+		if locale == "" {
+			continue
+		}
+		for _, s := range supported {
+			if strings.EqualFold(s, locale) {
+				return s
+			}
+		}
+		base := baseLanguage(locale)
+		var match string
+		for _, s := range supported {
+			if s == base {
+				return s
+			}
+			if match == "" && baseLanguage(s) == base {
+				match = s
+			}
+		}
+		if match != "" {
+			return match
+		}
+	}
+	return ""
+}
+
+// Localizer 返回 locale 语言的翻译器
+func (b *Bundle) Localizer(locale string) *Localizer {
+	return &Localizer{bundle: b, locale: Canonical(locale)}
+}
+
+// T 翻译消息，消息不存在时返回 key
+func (b *Bundle) T(locale string, key string, args ...interface{}) string {
+	if message, ok := b.Translate(locale, key, args...); ok {
+		return message
+	}
+	return key
+}
+
+// Translate 翻译消息，消息不存在时 ok 为 false
+//
+// args 为 "名称, 值" 形式的占位符参数，也可以是一个 potgo.Map。参数中有 count 时，
+// 根据语言的复数规则依次查找 key.zero（数量为 0 时）、key.复数形式、key.other 和 key
+func (b *Bundle) Translate(locale string, key string, args ...interface{}) (message string, ok bool) {
+	params := parseArgs(args)
+	count, hasCount := toInt(params["count"])
+
+	locale = Canonical(locale)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	// 先查找请求的语言及其上一级语言，再查找请求的语言内置的 HTTP 状态信息，最后查找默认语言
+	if message, ok = b.lookup(parentLocales(locale), key, params, count, hasCount); ok {
+		return message, true
+	}
+	if messages := httpMessages[baseLanguage(locale)]; messages != nil {
+		if message, ok = messages[key]; ok {
+			return message, true
+		}
+	}
+	if b.defaultLocale != "" && b.defaultLocale != locale {
+		return b.lookup(parentLocales(b.defaultLocale), key, params, count, hasCount)
+	}
+	return "", false
+}
+
+// lookup 依次在 locales 的 catalog 中查找消息
+func (b *Bundle) lookup(locales []string, key string, params map[string]interface{}, count int, hasCount bool) (string, bool) {
+	for _, l := range locales {
+		catalog := b.catalogs[l]
+		if catalog == nil {
+			continue
+		}
+		if hasCount {
+			if message, ok := b.plural(catalog, l, key, count); ok {
+				return format(message, params), true
+			}
+		} else if message, ok := catalog[key]; ok {
+			return format(message, params), true
+		}
+	}
+	return "", false
+}
+
+// plural 查找数量 n 对应的复数形式的消息
+func (b *Bundle) plural(catalog map[string]string, locale string, key string, n int) (string, bool) {
+	if n == 0 {
+		if message, ok := catalog[key+"."+Zero]; ok {
+			return message, true
+		}
+	}
+	lang := baseLanguage(locale)
+	rule, ok := b.rules[lang]
+	if !ok {
+		if rule, ok = pluralRules[lang]; !ok {
+			rule = pluralOneOther
+		}
+	}
+	for _, k := range []string{key + "." + rule(n), key + "." + Other, key} {
+		if message, ok := catalog[k]; ok {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// parentLocales 返回语言及其上一级语言，例如 zh-Hant-TW、zh-Hant、zh
+func parentLocales(locale string) []string {
+	var locales []string
+	for l := locale; l != ""; {
+		locales = append(locales, l)
+		i := strings.LastIndexByte(l, '-')
+		if i < 0 {
+			break
+		}
+		l = l[:i]
+	}
+	return locales
+}
+
+// Localizer 一种语言的翻译器，实现了 potgo.Translator
+type Localizer struct {
+	bundle *Bundle
+	locale string
+}
+
+var _ potgo.Translator = &Localizer{}
+
+// Locale 返回语言
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// Translate 翻译消息，消息不存在时 ok 为 false
+func (l *Localizer) Translate(key string, args ...interface{}) (string, bool) {
+	return l.bundle.Translate(l.locale, key, args...)
+}
+
+// T 翻译消息，消息不存在时返回 key
+func (l *Localizer) T(key string, args ...interface{}) string {
+	return l.bundle.T(l.locale, key, args...)
+}
+
+// Canonical 返回规范格式的语言，例如 zh_cn 返回 zh-CN，zh-hant-tw 返回 zh-Hant-TW
+func Canonical(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	for i, part := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(part)
+		case len(part) == 4:
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		case len(part) == 2 || len(part) == 3:
+			parts[i] = strings.ToUpper(part)
+		default:
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func baseLanguage(locale string) string {
+	if i := strings.IndexByte(locale, '-'); i >= 0 {
+		return locale[:i]
+	}
+	return locale
+}
+
+// parseArgs 解析占位符参数
+func parseArgs(args []interface{}) map[string]interface{} {
+	if len(args) == 1 {
+		switch m := args[0].(type) {
+		case potgo.Map:
+			return m
+		case map[string]interface{}:
+			return m
+		}
+	}
+	params := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		params[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return params
+}
+
+// format 替换消息中的 {名称} 占位符，没有对应参数的占位符保持不变
+func format(message string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(message, "{") {
+		return message
+	}
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(message, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(message[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		value, ok := params[message[start+1:end]]
+		if !ok {
+			sb.WriteString(message[:end+1])
+		} else {
+			sb.WriteString(message[:start])
+			sb.WriteString(fmt.Sprint(value))
+		}
+		message = message[end+1:]
+	}
+	sb.WriteString(message)
+	return sb.String()
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float32:
+		return int(n), true
+	case float64:
+		return int(n), true
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	}
+	return 0, false
+}
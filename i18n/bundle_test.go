@@ -0,0 +1,116 @@
+package i18n
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalogs = fstest.MapFS{
+	"locales/en.lang": {Data: []byte(`
+# English
+hello = Hello, {name}!
+multiline = first\nsecond \
+  continued
+
+[cart]
+items.zero  = Your cart is empty
+items.one   = {count} item
+items.other = {count} items
+`)},
+	"locales/zh-CN.lang": {Data: []byte(`
+hello = 你好，{name}！
+
+[cart]
+items.zero  = 购物车是空的
+items.other = 购物车中有 {count} 件商品
+`)},
+	"locales/ru.lang": {Data: []byte(`
+apples.one  = {count} яблоко
+apples.few  = {count} яблока
+apples.many = {count} яблок
+`)},
+	"locales/readme.txt": {Data: []byte("ignored")},
+}
+
+func newTestBundle(t *testing.T) *Bundle {
+	b := NewBundle("en")
+	assert.Nil(t, b.LoadFS(testCatalogs))
+	return b
+}
+
+func TestBundle_Translate(t *testing.T) {
+	b := newTestBundle(t)
+
+	assert.Equal(t, "Hello, Tom!", b.T("en", "hello", "name", "Tom"))
+	assert.Equal(t, "你好，Tom！", b.T("zh-CN", "hello", potgo.Map{"name": "Tom"}))
+	assert.Equal(t, "你好，{name}！", b.T("zh_cn", "hello"))
+	assert.Equal(t, "first\nsecond continued", b.T("en", "multiline"))
+
+	// 复数
+	assert.Equal(t, "Your cart is empty", b.T("en", "cart.items", "count", 0))
+	assert.Equal(t, "1 item", b.T("en", "cart.items", "count", 1))
+	assert.Equal(t, "5 items", b.T("en", "cart.items", "count", int64(5)))
+	assert.Equal(t, "购物车是空的", b.T("zh-CN", "cart.items", "count", 0))
+	assert.Equal(t, "购物车中有 1 件商品", b.T("zh-CN", "cart.items", "count", 1))
+	assert.Equal(t, "1 яблоко", b.T("ru", "apples", "count", 1))
+	assert.Equal(t, "3 яблока", b.T("ru", "apples", "count", 3))
+	assert.Equal(t, "11 яблок", b.T("ru", "apples", "count", 11))
+	assert.Equal(t, "21 яблоко", b.T("ru", "apples", "count", 21))
+
+	// 找不到消息时使用上一级语言和默认语言
+	assert.Equal(t, "你好，A！", b.T("zh-CN-x-test", "hello", "name", "A"))
+	assert.Equal(t, "Hello, A!", b.T("fr", "hello", "name", "A"))
+	assert.Equal(t, "missing", b.T("en", "missing"))
+
+	// 内置的 HTTP 状态信息
+	message, ok := b.Translate("zh-CN", "http.404")
+	assert.True(t, ok)
+	assert.Equal(t, "页面未找到", message)
+	message, ok = b.Translate("en-US", "http.404")
+	assert.True(t, ok)
+	assert.Equal(t, "Not Found", message)
+	_, ok = b.Translate("fr", "http.404")
+	assert.False(t, ok)
+
+	// 默认语言的 catalog 不覆盖其它语言内置的 HTTP 状态信息
+	b.AddMessages("en", map[string]string{"http.404": "Page not found"})
+	assert.Equal(t, "页面未找到", b.T("zh-CN", "http.404"))
+	assert.Equal(t, "Page not found", b.T("en", "http.404"))
+	assert.Equal(t, "Page not found", b.T("fr", "http.404"))
+
+	b.SetPluralRule("en", func(n int) string { return Other })
+	assert.Equal(t, "1 items", b.T("en", "cart.items", "count", 1))
+}
+
+func TestBundle_Match(t *testing.T) {
+	b := newTestBundle(t)
+	assert.Equal(t, []string{"en", "ru", "zh-CN"}, b.Locales())
+
+	assert.Equal(t, "zh-CN", b.Match("zh-cn"))
+	assert.Equal(t, "zh-CN", b.Match("zh-TW"))
+	assert.Equal(t, "en", b.Match("en-US"))
+	assert.Equal(t, "ru", b.Match("", "de", "ru"))
+	assert.Equal(t, "", b.Match("de"))
+}
+
+func TestParseCatalog(t *testing.T) {
+	_, err := parseCatalog([]byte("[section"))
+	assert.NotNil(t, err)
+	_, err = parseCatalog([]byte("no equals sign"))
+	assert.NotNil(t, err)
+
+	messages, err := parseCatalog([]byte("\ufeffa = 1\n; comment\nb = x=y\nc = \\\\"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "x=y", "c": `\`}, messages)
+
+	assert.NotNil(t, NewBundle("en").LoadFS(fstest.MapFS{"en.lang": {Data: []byte("bad")}}))
+}
+
+func TestCanonical(t *testing.T) {
+	assert.Equal(t, "zh-CN", Canonical("zh_cn"))
+	assert.Equal(t, "zh-Hant-TW", Canonical("ZH-hant-tw"))
+	assert.Equal(t, "en", Canonical(" EN "))
+}
@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// parseCatalog 解析消息文件
+//
+//	# 注释
+//	hello = 你好，{name}
+//
+//	[cart]
+//	items.zero  = 购物车是空的
+//	items.other = 购物车中有 {count} 件商品
+//
+// [section] 之后的键以 "section." 为前缀，值中的 \n 表示换行，行尾的 \ 表示值在下一行继续
+func parseCatalog(data []byte) (map[string]string, error) {
+	messages := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	var section, key, value string
+	continued := false
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // UTF-8 BOM
+		}
+
+		if continued {
+			value += line
+		} else {
+			if line == "" || line[0] == '#' || line[0] == ';' {
+				continue
+			}
+			if line[0] == '[' {
+				if line[len(line)-1] != ']' {
+					return nil, fmt.Errorf("i18n: line %d: invalid section %q", n, line)
+				}
+				section = strings.TrimSpace(line[1 : len(line)-1])
+				continue
+			}
+			eq := strings.IndexByte(line, '=')
+			if eq <= 0 {
+				return nil, fmt.Errorf("i18n: line %d: missing '=' in %q", n, line)
+			}
+			key = strings.TrimSpace(line[:eq])
+			if section != "" {
+				key = section + "." + key
+			}
+			value = strings.TrimSpace(line[eq+1:])
+		}
+
+		continued = strings.HasSuffix(value, `\`) && !strings.HasSuffix(value, `\\`)
+		if continued {
+			value = strings.TrimSuffix(value, `\`)
+			continue
+		}
+		messages[key] = unescape(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if continued {
+		messages[key] = unescape(value)
+	}
+	return messages, nil
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t")
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
@@ -0,0 +1,47 @@
+package i18n
+
+// httpMessages 内置的 HTTP 状态信息，键为 http.状态码，请求的语言的 catalog 中的同名消息优先，
+// 内置信息优先于默认语言的 catalog，
+// 其它语言没有翻译时错误处理程序收到 http.StatusText 返回的英文信息
+var httpMessages = map[string]map[string]string{
+	"en": {
+		"http.400": "Bad Request",
+		"http.401": "Unauthorized",
+		"http.403": "Forbidden",
+		"http.404": "Not Found",
+		"http.405": "Method Not Allowed",
+		"http.406": "Not Acceptable",
+		"http.408": "Request Timeout",
+		"http.409": "Conflict",
+		"http.410": "Gone",
+		"http.413": "Request Entity Too Large",
+		"http.415": "Unsupported Media Type",
+		"http.422": "Unprocessable Entity",
+		"http.429": "Too Many Requests",
+		"http.500": "Internal Server Error",
+		"http.501": "Not Implemented",
+		"http.502": "Bad Gateway",
+		"http.503": "Service Unavailable",
+		"http.504": "Gateway Timeout",
+	},
+	"zh": {
+		"http.400": "请求错误",
+		"http.401": "未授权",
+		"http.403": "禁止访问",
+		"http.404": "页面未找到",
+		"http.405": "请求方法不允许",
+		"http.406": "无法接受",
+		"http.408": "请求超时",
+		"http.409": "请求冲突",
+		"http.410": "资源已删除",
+		"http.413": "请求实体过大",
+		"http.415": "不支持的媒体类型",
+		"http.422": "无法处理的请求",
+		"http.429": "请求过多",
+		"http.500": "服务器内部错误",
+		"http.501": "尚未实现",
+		"http.502": "网关错误",
+		"http.503": "服务不可用",
+		"http.504": "网关超时",
+	},
+}
@@ -0,0 +1,108 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/icodechef/potgo"
+)
+
+// Config 语言选择中间件配置
+type Config struct {
+	Param  string // 路径参数名称，默认为 lang，设置为 "-" 时不使用
+	Query  string // 查询参数名称，默认为 lang，设置为 "-" 时不使用
+	Cookie string // cookie 名称，默认为 lang，设置为 "-" 时不使用
+}
+
+// Middleware 返回语言选择中间件
+//
+// 依次使用路径参数、查询参数、cookie 和 Accept-Language 请求头中第一个支持的语言，都不支持时使用默认语言。
+// 之后可以使用 Context.T 翻译消息，视图中使用 t 函数
+//
+//	{{ t "cart.items" "count" 3 }}
+func Middleware(bundle *Bundle, config ...Config) potgo.HandlerFunc {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	cfg.Param = defaultName(cfg.Param)
+	cfg.Query = defaultName(cfg.Query)
+	cfg.Cookie = defaultName(cfg.Cookie)
+
+	return func(c *potgo.Context) error {
+		var candidates []string
+		if cfg.Param != "" {
+			candidates = append(candidates, c.Param(cfg.Param))
+		}
+		if cfg.Query != "" {
+			candidates = append(candidates, c.Query(cfg.Query))
+		}
+		if cfg.Cookie != "" {
+			if value, err := c.GetCookie(cfg.Cookie); err == nil {
+				candidates = append(candidates, value)
+			}
+		}
+		candidates = append(candidates, ParseAcceptLanguage(c.Request.Header.Get("Accept-Language"))...)
+
+		locale := bundle.Match(candidates...)
+		if locale == "" {
+			locale = bundle.DefaultLocale()
+		}
+		potgo.TranslatorKey.Set(c, bundle.Localizer(locale))
+		c.Response.Header().Add("Vary", "Accept-Language")
+		return c.Next()
+	}
+}
+
+func defaultName(name string) string {
+	switch name {
+	case "":
+		return "lang"
+	case "-":
+		return ""
+	}
+	return name
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 请求头，返回按权重从高到低排序的语言
+//
+//	Accept-Language: zh-CN,zh;q=0.9,en;q=0.8
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+	var languages []language
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			tag = strings.TrimSpace(part[:i])
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					continue
+				}
+				q = v
+			}
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		languages = append(languages, language{tag, q})
+	}
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
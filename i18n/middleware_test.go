@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	app := potgo.New()
+	app.Use(Middleware(newTestBundle(t)))
+	app.GET("/hello", func(c *potgo.Context) error {
+		return c.Text("%s %s", c.Locale(), c.T("hello", "name", "A"))
+	})
+	app.GET("/{lang}/hello", func(c *potgo.Context) error {
+		return c.Text("%s %s", c.Locale(), c.T("hello", "name", "A"))
+	})
+	app.GET("/forbidden", func(c *potgo.Context) error {
+		return potgo.NewHTTPError(http.StatusForbidden)
+	})
+	app.GET("/custom", func(c *potgo.Context) error {
+		return potgo.NewHTTPError(http.StatusForbidden, "custom message")
+	})
+
+	tests := []struct {
+		path   string
+		header string
+		cookie string
+		body   string
+	}{
+		{"/hello", "", "", "en Hello, A!"},
+		{"/hello", "de, zh-CN;q=0.8, en;q=0.5", "", "zh-CN 你好，A！"},
+		{"/hello", "en;q=0.5, zh-TW;q=0.9", "", "zh-CN 你好，A！"},
+		{"/hello", "zh-CN", "en", "en Hello, A!"},
+		{"/hello?lang=zh-CN", "", "en", "zh-CN 你好，A！"},
+		{"/hello?lang=xx", "", "zh-CN", "zh-CN 你好，A！"},
+		{"/zh-CN/hello?lang=en", "", "", "zh-CN 你好，A！"},
+		{"/forbidden", "zh-CN", "", "禁止访问\n"},
+		{"/forbidden", "en", "", "Forbidden\n"},
+		{"/custom", "zh-CN", "", "custom message\n"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Accept-Language", tt.header)
+		}
+		if tt.cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, tt.body, res.Body.String(), tt.path)
+		assert.Equal(t, "Accept-Language", res.Header().Get("Vary"))
+	}
+}
+
+func TestMiddleware_View(t *testing.T) {
+	app := potgo.New()
+	_ = app.RegisterView(potgo.HTMLFS(fstest.MapFS{
+		"cart.html": {Data: []byte(`<p>{{ t "cart.items" "count" .count }}</p><p>{{ t "hello" "name" "<b>" }}</p>`)},
+	}, ".", ".html"))
+	app.Use(Middleware(newTestBundle(t), Config{Query: "-", Cookie: "locale"}))
+	app.GET("/cart", func(c *potgo.Context) error {
+		return c.View("cart.html", potgo.Map{"count": 2})
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/cart?lang=en", nil)
+	req.AddCookie(&http.Cookie{Name: "locale", Value: "zh-CN"})
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "<p>购物车中有 2 件商品</p><p>你好，&lt;b&gt;！</p>", res.Body.String())
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"zh-CN", "zh", "en"}, ParseAcceptLanguage("en;q=0.8, zh-CN, zh;q=0.9, *;q=0.1, fr;q=0"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
@@ -0,0 +1,110 @@
+package i18n
+
+// 复数形式，与 CLDR 的复数类别相同，消息的键以复数形式为后缀，例如 items.one、items.other
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// PluralRule 复数规则，返回数量 n 对应的复数形式
+type PluralRule func(n int) string
+
+// pluralRules 内置的复数规则，键为语言，没有对应规则的语言使用 pluralOneOther
+var pluralRules = map[string]PluralRule{
+	"zh": pluralOther,
+	"ja": pluralOther,
+	"ko": pluralOther,
+	"vi": pluralOther,
+	"th": pluralOther,
+	"id": pluralOther,
+	"fr": pluralFrench,
+	"ru": pluralSlavic,
+	"uk": pluralSlavic,
+	"be": pluralSlavic,
+	"pl": pluralPolish,
+	"cs": pluralCzech,
+	"sk": pluralCzech,
+	"ar": pluralArabic,
+}
+
+func pluralOther(n int) string {
+	return Other
+}
+
+func pluralOneOther(n int) string {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+func pluralFrench(n int) string {
+	if n == 0 || n == 1 {
+		return One
+	}
+	return Other
+}
+
+func pluralSlavic(n int) string {
+	mod10, mod100 := abs(n)%10, abs(n)%100
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return One
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+func pluralPolish(n int) string {
+	mod10, mod100 := abs(n)%10, abs(n)%100
+	switch {
+	case n == 1:
+		return One
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
+
+func pluralCzech(n int) string {
+	switch {
+	case n == 1:
+		return One
+	case n >= 2 && n <= 4:
+		return Few
+	default:
+		return Other
+	}
+}
+
+func pluralArabic(n int) string {
+	mod100 := abs(n) % 100
+	switch {
+	case n == 0:
+		return Zero
+	case n == 1:
+		return One
+	case n == 2:
+		return Two
+	case mod100 >= 3 && mod100 <= 10:
+		return Few
+	case mod100 >= 11:
+		return Many
+	default:
+		return Other
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testTranslator map[string]string
+
+func (t testTranslator) Locale() string {
+	return "zh-CN"
+}
+
+func (t testTranslator) Translate(key string, args ...interface{}) (string, bool) {
+	message, ok := t[key]
+	return message, ok
+}
+
+func TestContext_T(t *testing.T) {
+	c := &Context{}
+	assert.Equal(t, "hello", c.T("hello"))
+	assert.Equal(t, "", c.Locale())
+
+	TranslatorKey.Set(c, testTranslator{"hello": "你好"})
+	assert.Equal(t, "你好", c.T("hello"))
+	assert.Equal(t, "missing", c.T("missing"))
+	assert.Equal(t, "zh-CN", c.Locale())
+}
+
+func TestHTTPError_Localized(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) error {
+		TranslatorKey.Set(c, testTranslator{"http.404": "页面未找到"})
+		return c.Next()
+	})
+	r.GET("/default", func(c *Context) error {
+		return NewHTTPError(http.StatusNotFound)
+	})
+	r.GET("/custom", func(c *Context) error {
+		return NewHTTPError(http.StatusNotFound, "no such user")
+	})
+	r.GET("/untranslated", func(c *Context) error {
+		return NewHTTPError(http.StatusConflict)
+	})
+
+	for path, body := range map[string]string{
+		"/default":      "页面未找到\n",
+		"/custom":       "no such user\n",
+		"/untranslated": "Conflict\n",
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, body, res.Body.String(), path)
+	}
+}
@@ -0,0 +1,64 @@
+package potgo
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Key 在上下文中保存数据使用的键
+//
+// 每个 Key 都是唯一的，即使名称相同也不会与其它 Key 或 Context.Set 使用的字符串键冲突。
+// 创建 Key 时可以指定值的类型，Set 会检查值的类型，从而保证 Get 取出的值可以安全地进行类型断言
+//
+//	var userKey = potgo.NewKey("user", (*User)(nil))
+//
+//	userKey.Set(c, user)
+//	user := userKey.MustGet(c).(*User)
+type Key struct {
+	name string
+	typ  reflect.Type
+}
+
+// NewKey 创建 Key，typ 为可选的值的类型样例，例如 (*User)(nil)、""、0
+func NewKey(name string, typ ...interface{}) *Key {
+	k := &Key{name: name}
+	if len(typ) > 0 && typ[0] != nil {
+		k.typ = reflect.TypeOf(typ[0])
+	}
+	return k
+}
+
+// String 返回 Key 的名称
+func (k *Key) String() string {
+	return k.name
+}
+
+// Set 在上下文中保存数据，如果值的类型与 Key 的类型不匹配则 panic
+func (k *Key) Set(c *Context, value interface{}) {
+	if k.typ != nil && value != nil && !reflect.TypeOf(value).AssignableTo(k.typ) {
+		panic(fmt.Sprintf("potgo: value of key %q must be %s, got %T", k.name, k.typ, value))
+	}
+	c.setValue(k, value)
+}
+
+// Get 从上下文中检索数据
+func (k *Key) Get(c *Context) (value interface{}, exists bool) {
+	return c.value(k)
+}
+
+// MustGet 从上下文中检索数据，如果不存在则 panic
+func (k *Key) MustGet(c *Context) interface{} {
+	value, err := c.lookup(k)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// Scan 从上下文中检索数据并赋值给 ptr 指向的变量
+//
+//	var user *User
+//	err := userKey.Scan(c, &user)
+func (k *Key) Scan(c *Context, ptr interface{}) error {
+	return c.scan(k, ptr)
+}
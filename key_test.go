@@ -0,0 +1,60 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testKeyUser struct {
+	Name string
+}
+
+func TestKey_SetGet(t *testing.T) {
+	c := &Context{}
+	k1 := NewKey("user")
+	k2 := NewKey("user")
+
+	k1.Set(c, "foo")
+	c.Set("user", "bar")
+
+	value, ok := k1.Get(c)
+	assert.True(t, ok)
+	assert.Equal(t, "foo", value)
+
+	value, ok = k2.Get(c)
+	assert.False(t, ok)
+	assert.Nil(t, value)
+
+	value, _ = c.Get("user")
+	assert.Equal(t, "bar", value)
+	assert.Equal(t, "user", k1.String())
+}
+
+func TestKey_Typed(t *testing.T) {
+	c := &Context{}
+	userKey := NewKey("user", (*testKeyUser)(nil))
+
+	assert.Panics(t, func() {
+		userKey.Set(c, "foo")
+	})
+
+	userKey.Set(c, &testKeyUser{Name: "foo"})
+	assert.Equal(t, "foo", userKey.MustGet(c).(*testKeyUser).Name)
+
+	var user *testKeyUser
+	assert.Nil(t, userKey.Scan(c, &user))
+	assert.Equal(t, "foo", user.Name)
+
+	var name string
+	err := userKey.Scan(c, &name)
+	assert.Equal(t, `potgo: value of key "user" is *potgo.testKeyUser, not string`, err.Error())
+}
+
+func TestKey_MustGet(t *testing.T) {
+	c := &Context{}
+	k := NewKey("undefined")
+
+	assert.PanicsWithError(t, `potgo: key "undefined" does not exist in context`, func() {
+		k.MustGet(c)
+	})
+}
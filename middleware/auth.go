@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/icodechef/potgo"
+)
+
+// AuthUserKey 认证中间件使用 Context.Set 保存用户名或用户标识使用的键，值的类型为 string
+const AuthUserKey = "middleware.auth.user"
+
+// BasicAuthValidator 验证用户名和密码的函数
+type BasicAuthValidator func(c *potgo.Context, username, password string) (bool, error)
+
+// BasicAuth 返回 HTTP Basic 认证中间件，accounts 为用户名和密码的映射
+//
+// 认证成功后用户名保存在上下文中，可以使用 c.GetString(middleware.AuthUserKey) 获取
+func BasicAuth(accounts map[string]string, realm ...string) potgo.HandlerFunc {
+	return BasicAuthWithValidator(func(c *potgo.Context, username, password string) (bool, error) {
+		expected, ok := accounts[username]
+		// 用户不存在时也进行比较，避免通过响应时间判断用户是否存在
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1 && ok {
+			return true, nil
+		}
+		return false, nil
+	}, realm...)
+}
+
+// BasicAuthWithValidator 返回使用指定函数验证用户名和密码的 HTTP Basic 认证中间件
+func BasicAuthWithValidator(validator BasicAuthValidator, realm ...string) potgo.HandlerFunc {
+	challenge := "Basic realm=" + strconv.Quote(authRealm(realm)) + `, charset="UTF-8"`
+
+	return func(c *potgo.Context) error {
+		username, password, ok := c.Request.BasicAuth()
+		if ok {
+			valid, err := validator(c, username, password)
+			if err != nil {
+				return err
+			}
+			if valid {
+				c.Set(AuthUserKey, username)
+				return c.Next()
+			}
+		}
+		return unauthorized(c, challenge)
+	}
+}
+
+// KeyAuthValidator 验证密钥的函数，可以在其中将密钥对应的用户保存到上下文中
+type KeyAuthValidator func(c *potgo.Context, key string) (bool, error)
+
+// KeyAuthConfig 密钥认证中间件配置
+type KeyAuthConfig struct {
+	// Header 读取密钥的请求头，默认为 Authorization
+	Header string
+	// Scheme 请求头中密钥的前缀，Header 为 Authorization 时默认为 Bearer
+	Scheme string
+	// Query 请求头中没有密钥时，读取密钥的查询字符串参数
+	Query string
+	// Realm 认证域，默认为 Restricted
+	Realm string
+}
+
+// KeyAuth 返回 Bearer 令牌或 API 密钥认证中间件
+//
+//	api.Use(middleware.KeyAuth(func(c *potgo.Context, key string) (bool, error) {
+//		return key == "secret", nil
+//	}, middleware.KeyAuthConfig{Header: "X-API-Key"}))
+func KeyAuth(validator KeyAuthValidator, config ...KeyAuthConfig) potgo.HandlerFunc {
+	var cfg KeyAuthConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Header == "" {
+		cfg.Header = "Authorization"
+		if cfg.Scheme == "" {
+			cfg.Scheme = "Bearer"
+		}
+	}
+
+	scheme := cfg.Scheme
+	if scheme == "" {
+		scheme = "APIKey"
+	}
+	challenge := scheme + " realm=" + strconv.Quote(authRealm([]string{cfg.Realm}))
+
+	return func(c *potgo.Context) error {
+		key := authToken(c.Request, cfg.Header, cfg.Scheme)
+		if key == "" && cfg.Query != "" {
+			key = c.Query(cfg.Query)
+		}
+		if key == "" {
+			return unauthorized(c, challenge)
+		}
+
+		valid, err := validator(c, key)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return unauthorized(c, challenge+`, error="invalid_token"`)
+		}
+		return c.Next()
+	}
+}
+
+// authToken 从请求头中读取令牌，scheme 不为空时需要匹配前缀
+func authToken(req *http.Request, header, scheme string) string {
+	value := strings.TrimSpace(req.Header.Get(header))
+	if scheme == "" {
+		return value
+	}
+	if len(value) > len(scheme) && strings.EqualFold(value[:len(scheme)], scheme) && value[len(scheme)] == ' ' {
+		return strings.TrimSpace(value[len(scheme)+1:])
+	}
+	return ""
+}
+
+func authRealm(realm []string) string {
+	if len(realm) > 0 && realm[0] != "" {
+		return realm[0]
+	}
+	return "Restricted"
+}
+
+// unauthorized 设置 WWW-Authenticate 响应头并返回 401 错误
+func unauthorized(c *potgo.Context, challenge string) error {
+	c.Response.Header().Set("WWW-Authenticate", challenge)
+	return potgo.NewHTTPError(http.StatusUnauthorized)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicAuth(t *testing.T) {
+	app := potgo.New()
+	app.Use(BasicAuth(map[string]string{"admin": "secret"}, "Admin"))
+	app.GET("/", func(c *potgo.Context) error {
+		user, err := c.GetString(AuthUserKey)
+		assert.Nil(t, err)
+		return c.Text(user)
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, `Basic realm="Admin", charset="UTF-8"`, res.Header().Get("WWW-Authenticate"))
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "wrong")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("nobody", "")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("admin", "secret")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "admin", res.Body.String())
+}
+
+func TestKeyAuth(t *testing.T) {
+	validator := func(c *potgo.Context, key string) (bool, error) {
+		if key == "valid-key" {
+			c.Set(AuthUserKey, "api")
+			return true, nil
+		}
+		return false, nil
+	}
+
+	app := potgo.New()
+	app.GET("/bearer", KeyAuth(validator), func(c *potgo.Context) error {
+		return c.Text(c.MustGet(AuthUserKey).(string))
+	})
+	app.GET("/header", KeyAuth(validator, KeyAuthConfig{Header: "X-API-Key", Query: "api_key"}), func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	tests := []struct {
+		path      string
+		header    string
+		value     string
+		code      int
+		challenge string
+	}{
+		{"/bearer", "", "", http.StatusUnauthorized, `Bearer realm="Restricted"`},
+		{"/bearer", "Authorization", "Bearer bad", http.StatusUnauthorized, `Bearer realm="Restricted", error="invalid_token"`},
+		{"/bearer", "Authorization", "valid-key", http.StatusUnauthorized, `Bearer realm="Restricted"`},
+		{"/bearer", "Authorization", "bearer valid-key", http.StatusOK, ""},
+		{"/header", "X-API-Key", "valid-key", http.StatusOK, ""},
+		{"/header?api_key=valid-key", "", "", http.StatusOK, ""},
+		{"/header", "", "", http.StatusUnauthorized, `APIKey realm="Restricted"`},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, tt.code, res.Code, tt.path)
+		assert.Equal(t, tt.challenge, res.Header().Get("WWW-Authenticate"), tt.path)
+	}
+}
@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/icodechef/potgo"
+)
+
+// CompressConfig 压缩中间件配置
+type CompressConfig struct {
+	// MinLength 响应主体小于此长度时不压缩，默认为 1024 字节
+	MinLength int
+	// ExcludedContentTypes 不压缩的 Content-Type 前缀，默认为图片、音视频和常见的压缩格式
+	ExcludedContentTypes []string
+}
+
+// defaultExcludedContentTypes 已经压缩过的内容类型
+var defaultExcludedContentTypes = []string{
+	"image/", "audio/", "video/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-rar-compressed", "application/x-7z-compressed",
+	"application/pdf", "application/octet-stream",
+}
+
+// Compress 返回响应压缩中间件，level 为压缩级别，可以使用 gzip.DefaultCompression 等常量
+//
+// 根据 Accept-Encoding 选择 gzip 或 deflate 压缩，跳过 HEAD 请求、Range 请求、小于 MinLength 的响应
+// 和已经压缩过的内容类型
+func Compress(level int, config ...CompressConfig) potgo.HandlerFunc {
+	var cfg CompressConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.MinLength == 0 {
+		cfg.MinLength = 1024
+	}
+	if cfg.ExcludedContentTypes == nil {
+		cfg.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+
+	// 级别错误时立即报错，而不是在请求时
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic(err)
+	}
+
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, level)
+			return w
+		}},
+	}
+
+	return func(c *potgo.Context) error {
+		c.Response.Header().Add("Vary", "Accept-Encoding")
+
+		if c.Request.Method == http.MethodHead || c.Request.Header.Get("Range") != "" {
+			return c.Next()
+		}
+		encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			return c.Next()
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Response.Writer,
+			config:         &cfg,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			status:         http.StatusOK,
+		}
+		c.Response.Writer = cw
+		defer func() {
+			cw.close()
+			// 已经开始压缩时，错误处理程序写入的响应也需要压缩，在请求结束后再结束压缩
+			c.Response.After(cw.finish)
+		}()
+
+		return c.Next()
+	}
+}
+
+// compressor gzip.Writer 和 zlib.Writer 共有的方法
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter 在写入第一块足够大的数据时决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+	config      *CompressConfig
+	encoding    string
+	pool        *sync.Pool
+	writer      compressor
+	buf         []byte
+	status      int
+	wroteHeader bool // 是否调用过 WriteHeader
+	decided     bool // 是否已经决定是否压缩
+	closed      bool // 中间件是否已经返回
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+
+	// 中间件返回后写入的响应不压缩，例如错误处理程序写入的错误信息，
+	// 没有响应主体的状态码也不需要等待
+	if w.closed || code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.writer != nil {
+			return w.writer.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.config.MinLength {
+		if err := w.flushBuffer(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush 刷新时不再等待更多的数据
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.flushBuffer(!w.closed)
+	}
+	if w.writer != nil {
+		_ = w.writer.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Unwrap 返回原始的 http.ResponseWriter
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushBuffer 决定是否压缩并写入缓冲的数据
+func (w *compressWriter) flushBuffer(allowCompress bool) error {
+	w.decide(allowCompress && w.shouldCompress())
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// decide 发送响应头，compress 为 true 时开始压缩
+func (w *compressWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.ResponseWriter.Header()
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		w.writer = w.pool.Get().(compressor)
+		w.writer.Reset(w.ResponseWriter)
+	} else if len(w.buf) > 0 && header.Get("Content-Length") == "" && header.Get("Transfer-Encoding") == "" {
+		header.Set("Content-Length", strconv.Itoa(len(w.buf)))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+		header.Set("Content-Type", contentType)
+	}
+	for _, excluded := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// close 在中间件返回时写入缓冲的数据
+//
+// 没有写入任何数据时不发送响应头，之后的 WriteHeader 和 Write 直接交给原始的 http.ResponseWriter，
+// 例如 HandlerFunc 返回错误后由错误处理程序写入的响应
+func (w *compressWriter) close() {
+	w.closed = true
+	if !w.decided && (w.wroteHeader || len(w.buf) > 0) {
+		_ = w.flushBuffer(len(w.buf) >= w.config.MinLength)
+	}
+}
+
+// finish 在请求结束后结束压缩并将压缩器放回对象池
+func (w *compressWriter) finish() {
+	if w.writer != nil {
+		_ = w.writer.Close()
+		w.pool.Put(w.writer)
+		w.writer = nil
+	}
+}
+
+// negotiateEncoding 根据 Accept-Encoding 选择压缩方式，相同权重时优先使用 gzip
+//
+// * 只匹配没有单独列出的压缩方式，例如 "gzip;q=0, *" 不使用 gzip
+func negotiateEncoding(accept string) string {
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name = strings.TrimSpace(part[:i])
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		qs[strings.ToLower(name)] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range [...]string{"gzip", "deflate"} {
+		q, ok := qs[name]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
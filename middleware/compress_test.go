@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var largeBody = strings.Repeat("hello world ", 200)
+
+func doCompressRequest(app *potgo.Application, path, encoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if encoding != "" {
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestCompress_Gzip(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/large", func(c *potgo.Context) error {
+		return c.Text(largeBody)
+	})
+
+	res := doCompressRequest(app, "/large", "deflate;q=0.5, gzip")
+
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", res.Header().Get("Vary"))
+	assert.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+
+	r, err := gzip.NewReader(res.Body)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(r)
+	assert.Equal(t, largeBody, string(body))
+}
+
+func TestCompress_Deflate(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/large", func(c *potgo.Context) error {
+		return c.Text(largeBody)
+	})
+
+	res := doCompressRequest(app, "/large", "gzip;q=0.5, deflate")
+
+	assert.Equal(t, "deflate", res.Header().Get("Content-Encoding"))
+	r, err := zlib.NewReader(res.Body)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(r)
+	assert.Equal(t, largeBody, string(body))
+}
+
+func TestCompress_Skip(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/large", func(c *potgo.Context) error {
+		return c.Text(largeBody)
+	})
+	app.GET("/small", func(c *potgo.Context) error {
+		return c.Text("hello")
+	})
+	app.GET("/image", func(c *potgo.Context) error {
+		c.ContentType("image/png")
+		return c.Text(largeBody)
+	})
+	app.GET("/empty", func(c *potgo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	res := doCompressRequest(app, "/large", "")
+	assert.Equal(t, "", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, largeBody, res.Body.String())
+
+	res = doCompressRequest(app, "/small", "gzip")
+	assert.Equal(t, "", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, "hello", res.Body.String())
+
+	res = doCompressRequest(app, "/image", "gzip")
+	assert.Equal(t, "", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, largeBody, res.Body.String())
+
+	res = doCompressRequest(app, "/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "", res.Header().Get("Content-Encoding"))
+
+	req, _ := http.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-10")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	assert.Equal(t, "", rec.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_Flush(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/stream", func(c *potgo.Context) error {
+		_, _ = c.Write([]byte("hello"))
+		c.Response.Flush()
+		_, err := c.Write([]byte(" world"))
+		return err
+	})
+
+	res := doCompressRequest(app, "/stream", "gzip")
+
+	assert.True(t, res.Flushed)
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	r, err := gzip.NewReader(res.Body)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(r)
+	assert.Equal(t, "hello world", string(body))
+}
+
+func TestCompress_Error(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/forbidden", func(c *potgo.Context) error {
+		return potgo.NewHTTPError(http.StatusForbidden)
+	})
+	app.GET("/large", func(c *potgo.Context) error {
+		return potgo.NewHTTPError(http.StatusInternalServerError, largeBody)
+	})
+
+	req, _ := http.NewRequest("GET", "/forbidden", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+	assert.Equal(t, "Forbidden\n", res.Body.String())
+
+	// 错误处理程序写入的响应不压缩
+	req, _ = http.NewRequest("GET", "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, "", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, largeBody+"\n", res.Body.String())
+}
+
+func TestCompress_ErrorAfterWrite(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression))
+	app.GET("/test", func(c *potgo.Context) error {
+		_ = c.Text(largeBody)
+		return errors.New("failed")
+	})
+
+	res := doCompressRequest(app, "/test", "gzip")
+
+	// 已经开始压缩，错误处理程序写入的数据也被压缩
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	r, err := gzip.NewReader(res.Body)
+	assert.Nil(t, err)
+	body, err := ioutil.ReadAll(r)
+	assert.Nil(t, err)
+	assert.Equal(t, largeBody+"failed\n", string(body))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", negotiateEncoding("gzip, deflate, br"))
+	assert.Equal(t, "deflate", negotiateEncoding("gzip;q=0.2, deflate;q=0.8"))
+	assert.Equal(t, "gzip", negotiateEncoding("*"))
+	assert.Equal(t, "", negotiateEncoding("br"))
+	assert.Equal(t, "", negotiateEncoding("gzip;q=0"))
+	assert.Equal(t, "deflate", negotiateEncoding("gzip;q=0, *"))
+	assert.Equal(t, "", negotiateEncoding("gzip;q=0, deflate;q=0, *"))
+	assert.Equal(t, "deflate", negotiateEncoding("*;q=0.5, deflate"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/icodechef/potgo"
+)
+
+// CORSConfig CORS 中间件配置
+type CORSConfig struct {
+	// AllowOrigins 允许的来源，支持 * 和 https://*.example.com 形式的子域名通配符，默认为 *，
+	// AllowCredentials 为 true 时不能使用 *
+	AllowOrigins []string
+	// AllowOriginFunc 自定义检查来源的函数，设置后忽略 AllowOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 允许的请求方法，默认为 GET、POST、PUT、DELETE、PATCH、HEAD
+	AllowMethods []string
+	// AllowHeaders 允许的请求头，为空时允许预检请求中 Access-Control-Request-Headers 列出的所有请求头
+	AllowHeaders []string
+	// ExposeHeaders 允许客户端读取的响应头
+	ExposeHeaders []string
+	// AllowCredentials 是否允许发送 cookie 等凭据
+	AllowCredentials bool
+	// MaxAge 预检请求结果的缓存时间，单位为秒
+	MaxAge int
+}
+
+// CORS 返回 CORS 中间件
+//
+// 预检请求由中间件直接响应。没有注册 OPTIONS 路由时，预检请求会执行匹配的路由的中间件，
+// 所以在应用、路由分组或者路由上使用的 CORS 中间件都可以响应预检请求，CORS 中间件应该在身份验证等中间件之前添加。
+//
+// AllowCredentials 为 true 时必须在 AllowOrigins 中列出允许的来源或者设置 AllowOriginFunc，否则 panic
+func CORS(config ...CORSConfig) potgo.HandlerFunc {
+	var cfg CORSConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if len(cfg.AllowOrigins) == 0 {
+		cfg.AllowOrigins = []string{"*"}
+	}
+	if len(cfg.AllowMethods) == 0 {
+		cfg.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut,
+			http.MethodDelete, http.MethodPatch, http.MethodHead}
+	}
+
+	allowAll := false
+	for _, o := range cfg.AllowOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+	}
+	if allowAll && cfg.AllowCredentials && cfg.AllowOriginFunc == nil {
+		panic("middleware: CORS AllowCredentials cannot be used with AllowOrigins \"*\", list the origins or set AllowOriginFunc")
+	}
+	allowMethods := strings.Join(cfg.AllowMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposeHeaders, ", ")
+	maxAge := ""
+	if cfg.MaxAge > 0 {
+		maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+
+	return func(c *potgo.Context) error {
+		header := c.Response.Header()
+		origin := c.Request.Header.Get("Origin")
+		preflight := c.Request.Method == http.MethodOptions &&
+			c.Request.Header.Get("Access-Control-Request-Method") != ""
+
+		// 允许的来源与请求相关时，响应需要按 Origin 缓存
+		if !allowAll || cfg.AllowCredentials || cfg.AllowOriginFunc != nil {
+			header.Add("Vary", "Origin")
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" || !cfg.allowOrigin(origin, allowAll) {
+			if preflight {
+				c.Abort()
+				return c.NoContent(http.StatusNoContent)
+			}
+			return c.Next()
+		}
+
+		if allowAll && !cfg.AllowCredentials && cfg.AllowOriginFunc == nil {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return c.Next()
+		}
+
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if h := c.Request.Header.Get("Access-Control-Request-Headers"); h != "" {
+			header.Set("Access-Control-Allow-Headers", h)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+
+		c.Abort()
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// allowOrigin 检查来源是否允许
+func (cfg *CORSConfig) allowOrigin(origin string, allowAll bool) bool {
+	if cfg.AllowOriginFunc != nil {
+		return cfg.AllowOriginFunc(origin)
+	}
+	if allowAll {
+		return true
+	}
+	for _, o := range cfg.AllowOrigins {
+		if i := strings.IndexByte(o, '*'); i >= 0 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) &&
+				!strings.Contains(origin[len(prefix):len(origin)-len(suffix)], "/") {
+				return true
+			}
+		} else if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
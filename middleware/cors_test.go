@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS_Preflight(t *testing.T) {
+	app := potgo.New()
+	app.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}))
+	app.GET("/users", func(c *potgo.Context) error {
+		return c.Text("users")
+	})
+	app.POST("/users", func(c *potgo.Context) error {
+		return c.Text("created")
+	})
+
+	// 没有注册 OPTIONS 路由
+	req, _ := http.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://api.example.org")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-Token")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "https://api.example.org", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", res.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Content-Type, X-Token", res.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", res.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, res.Header()["Vary"], "Origin")
+
+	// 不允许的来源
+	req, _ = http.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "", res.Header().Get("Access-Control-Allow-Origin"))
+
+	// 不存在的路径
+	req, _ = http.NewRequest("OPTIONS", "/undefined", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestCORS_Request(t *testing.T) {
+	app := potgo.New()
+	app.Use(CORS(CORSConfig{
+		ExposeHeaders: []string{"X-Total"},
+	}))
+	app.GET("/users", func(c *potgo.Context) error {
+		return c.Text("users")
+	})
+	app.POST("/users", func(c *potgo.Context) error {
+		return c.Text("created")
+	})
+
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "users", res.Body.String())
+	assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", res.Header().Get("Access-Control-Expose-Headers"))
+
+	req, _ = http.NewRequest("GET", "/users", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "", res.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSConfig_AllowOrigin(t *testing.T) {
+	cfg := &CORSConfig{AllowOrigins: []string{"https://example.com", "https://*.example.org"}}
+
+	assert.True(t, cfg.allowOrigin("https://example.com", false))
+	assert.True(t, cfg.allowOrigin("https://a.example.org", false))
+	assert.False(t, cfg.allowOrigin("https://example.org", false))
+	assert.False(t, cfg.allowOrigin("http://a.example.org", false))
+	assert.False(t, cfg.allowOrigin("https://evil.com/.example.org", false))
+
+	cfg.AllowOriginFunc = func(origin string) bool {
+		return origin == "https://func.com"
+	}
+	assert.True(t, cfg.allowOrigin("https://func.com", false))
+	assert.False(t, cfg.allowOrigin("https://example.com", false))
+}
+
+func TestCORS_Credentials(t *testing.T) {
+	// 允许所有来源时不能发送凭据
+	assert.Panics(t, func() {
+		CORS(CORSConfig{AllowCredentials: true})
+	})
+	assert.NotPanics(t, func() {
+		CORS(CORSConfig{AllowCredentials: true, AllowOriginFunc: func(origin string) bool { return true }})
+	})
+}
+
+func TestCORS_Group(t *testing.T) {
+	app := potgo.New()
+	api := app.Group("/api")
+	api.Use(CORS(CORSConfig{AllowOrigins: []string{"https://example.com"}}))
+	api.POST("/users", func(c *potgo.Context) error {
+		return c.Text("created")
+	})
+
+	// 预检请求执行路由分组的中间件
+	req, _ := http.NewRequest("OPTIONS", "/api/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "https://example.com", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST, PUT, DELETE, PATCH, HEAD", res.Header().Get("Access-Control-Allow-Methods"))
+
+	// 不是预检请求时自动响应 OPTIONS
+	req, _ = http.NewRequest("OPTIONS", "/api/users", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "POST, OPTIONS", res.Header().Get("Allow"))
+}
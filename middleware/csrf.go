@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/icodechef/potgo"
+)
+
+// CSRFMode CSRF 令牌的存储方式
+type CSRFMode uint8
+
+const (
+	// CSRFDoubleSubmit 双重提交，令牌保存在 cookie 中
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer 同步令牌，令牌保存在会话中，需要使用 sessions.Middleware
+	CSRFSynchronizer
+)
+
+const csrfTokenLength = 32
+
+// CSRFConfig CSRF 中间件配置
+type CSRFConfig struct {
+	Mode         CSRFMode // 令牌的存储方式，默认为 CSRFDoubleSubmit
+	CookieName   string   // 双重提交时保存令牌的 cookie 名称，同步令牌时为会话数据的键，默认为 _csrf
+	CookieMaxAge int      // cookie 有效期，单位为秒，默认为 12 小时
+	CookiePath   string
+	CookieDomain string
+	CookieSecure bool
+	HeaderName   string   // 提交令牌的请求头，默认为 X-CSRF-Token
+	FieldName    string   // 提交令牌的表单字段，默认为 _csrf
+	SafeMethods  []string // 不需要验证令牌的请求方法，默认为 GET、HEAD、OPTIONS、TRACE
+	// Exempt 不需要验证令牌的路由名称或者请求路径
+	Exempt []string
+	// TrustedOrigins 除了当前主机以外允许的来源，例如 https://example.com
+	TrustedOrigins []string
+	// SkipOriginCheck 不检查 Origin 和 Referer 请求头
+	SkipOriginCheck bool
+}
+
+// CSRF 返回 CSRF 防护中间件
+//
+// 对非安全方法的请求检查 Origin 或 Referer 请求头，并验证请求头或者表单字段中提交的令牌。
+// 验证失败时返回 403 错误，交给应用的错误处理程序处理。
+// 视图中可以使用 csrfToken 和 csrfField 函数输出令牌
+func CSRF(config ...CSRFConfig) potgo.HandlerFunc {
+	var cfg CSRFConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "_csrf"
+	}
+	if cfg.CookieMaxAge == 0 {
+		cfg.CookieMaxAge = 12 * 3600
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.HeaderName == "" {
+		cfg.HeaderName = "X-CSRF-Token"
+	}
+	if cfg.FieldName == "" {
+		cfg.FieldName = "_csrf"
+	}
+	if cfg.SafeMethods == nil {
+		cfg.SafeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}
+	}
+
+	safeMethods := make(map[string]bool, len(cfg.SafeMethods))
+	for _, method := range cfg.SafeMethods {
+		safeMethods[strings.ToUpper(method)] = true
+	}
+	exempt := make(map[string]bool, len(cfg.Exempt))
+	for _, s := range cfg.Exempt {
+		exempt[s] = true
+	}
+
+	return func(c *potgo.Context) error {
+		token := cfg.loadToken(c)
+		if token == nil {
+			var err error
+			if token, err = generateCSRFToken(); err != nil {
+				return err
+			}
+			cfg.saveToken(c, token)
+		}
+
+		potgo.CSRFTokenKey.Set(c, maskCSRFToken(token))
+		potgo.CSRFFieldKey.Set(c, cfg.FieldName)
+
+		if safeMethods[c.Request.Method] || isCSRFExempt(c, exempt) {
+			return c.Next()
+		}
+
+		if !cfg.SkipOriginCheck && !cfg.checkOrigin(c) {
+			return potgo.NewHTTPError(http.StatusForbidden, "CSRF origin check failed")
+		}
+
+		submitted := c.Request.Header.Get(cfg.HeaderName)
+		if submitted == "" {
+			submitted = c.FormValue(cfg.FieldName)
+		}
+		if !validCSRFToken(token, submitted) {
+			return potgo.NewHTTPError(http.StatusForbidden, "CSRF token mismatch")
+		}
+
+		return c.Next()
+	}
+}
+
+// loadToken 读取保存的令牌，不存在或者无效时返回 nil
+func (cfg *CSRFConfig) loadToken(c *potgo.Context) []byte {
+	var value string
+	if cfg.Mode == CSRFSynchronizer {
+		value, _ = c.Session().Get(cfg.CookieName).(string)
+	} else if cookie, err := c.Request.Cookie(cfg.CookieName); err == nil {
+		value = cookie.Value
+	}
+
+	token, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(token) != csrfTokenLength {
+		return nil
+	}
+	return token
+}
+
+func (cfg *CSRFConfig) saveToken(c *potgo.Context, token []byte) {
+	value := base64.RawURLEncoding.EncodeToString(token)
+	if cfg.Mode == CSRFSynchronizer {
+		c.Session().Set(cfg.CookieName, value)
+		return
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    value,
+		Path:     cfg.CookiePath,
+		Domain:   cfg.CookieDomain,
+		MaxAge:   cfg.CookieMaxAge,
+		Secure:   cfg.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// checkOrigin 检查请求来源是否为当前主机或者受信任的来源
+func (cfg *CSRFConfig) checkOrigin(c *potgo.Context) bool {
+	req := c.Request
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		origin = req.Header.Get("Referer")
+	}
+	if origin == "" {
+		// 没有来源信息时，HTTPS 请求拒绝，HTTP 请求由令牌验证
+		return c.Scheme() != "https"
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	// 同源要求协议和主机都相同，HTTPS 站点不接受 HTTP 来源
+	if strings.EqualFold(u.Scheme, c.Scheme()) && strings.EqualFold(u.Host, c.Host()) {
+		return true
+	}
+	for _, trusted := range cfg.TrustedOrigins {
+		if t, err := url.Parse(trusted); err == nil && strings.EqualFold(t.Scheme, u.Scheme) && strings.EqualFold(t.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCSRFExempt(c *potgo.Context, exempt map[string]bool) bool {
+	if len(exempt) == 0 {
+		return false
+	}
+	if exempt[c.Request.URL.Path] {
+		return true
+	}
+	if r := c.Route(); r != nil {
+		return exempt[r.GetName()] || exempt[r.Path()]
+	}
+	return false
+}
+
+func generateCSRFToken() ([]byte, error) {
+	token := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// maskCSRFToken 使用一次性密钥对令牌进行掩码，每次输出的令牌都不同，防止 BREACH 攻击
+func maskCSRFToken(token []byte) string {
+	otp := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(otp); err != nil {
+		return base64.RawURLEncoding.EncodeToString(token)
+	}
+
+	masked := make([]byte, csrfTokenLength*2)
+	copy(masked, otp)
+	for i := 0; i < csrfTokenLength; i++ {
+		masked[csrfTokenLength+i] = otp[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validCSRFToken 验证提交的令牌，支持掩码和未掩码的令牌
+func validCSRFToken(token []byte, submitted string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(submitted)
+	if err != nil {
+		return false
+	}
+
+	switch len(b) {
+	case csrfTokenLength * 2:
+		unmasked := make([]byte, csrfTokenLength)
+		for i := 0; i < csrfTokenLength; i++ {
+			unmasked[i] = b[i] ^ b[csrfTokenLength+i]
+		}
+		b = unmasked
+	case csrfTokenLength:
+	default:
+		return false
+	}
+	return subtle.ConstantTimeCompare(token, b) == 1
+}
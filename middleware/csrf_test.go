@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/icodechef/potgo/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func getCSRFToken(app *potgo.Application) (*http.Cookie, string) {
+	req, _ := http.NewRequest("GET", "/form", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res.Result().Cookies()[0], res.Body.String()
+}
+
+func TestCSRF(t *testing.T) {
+	app := potgo.New()
+	app.Use(CSRF())
+
+	app.GET("/form", func(c *potgo.Context) error {
+		return c.Text(c.CSRFToken())
+	})
+	app.POST("/submit", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	cookie, token := getCSRFToken(app)
+	assert.Equal(t, "_csrf", cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.NotEmpty(t, token)
+
+	// 请求头
+	req, _ := http.NewRequest("POST", "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-CSRF-Token", token)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "ok", res.Body.String())
+
+	// 表单字段
+	req, _ = http.NewRequest("POST", "/submit", strings.NewReader(url.Values{"_csrf": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "ok", res.Body.String())
+
+	// 没有令牌
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	req.AddCookie(cookie)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+
+	// 令牌与 cookie 不匹配
+	_, other := getCSRFToken(app)
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-CSRF-Token", other)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
+
+func TestCSRF_Origin(t *testing.T) {
+	app := potgo.New()
+	app.Use(CSRF(CSRFConfig{TrustedOrigins: []string{"https://trusted.com"}}))
+
+	app.GET("/form", func(c *potgo.Context) error {
+		return c.Text(c.CSRFToken())
+	})
+	app.POST("/submit", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	cookie, token := getCSRFToken(app)
+
+	tests := []struct {
+		origin string
+		code   int
+	}{
+		{"http://example.com", http.StatusOK},
+		{"https://trusted.com", http.StatusOK},
+		{"https://evil.com", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "http://example.com/submit", nil)
+		req.AddCookie(cookie)
+		req.Header.Set("X-CSRF-Token", token)
+		req.Header.Set("Origin", tt.origin)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, tt.code, res.Code, tt.origin)
+	}
+}
+
+func TestCSRF_OriginScheme(t *testing.T) {
+	app := potgo.New()
+	app.Use(CSRF())
+
+	app.GET("/form", func(c *potgo.Context) error {
+		return c.Text(c.CSRFToken())
+	})
+	app.POST("/submit", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	cookie, token := getCSRFToken(app)
+
+	tests := []struct {
+		origin string
+		code   int
+	}{
+		{"https://example.com", http.StatusOK},
+		{"http://example.com", http.StatusForbidden},
+		{"http://example.com/form", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "https://example.com/submit", nil)
+		req.TLS = &tls.ConnectionState{}
+		req.AddCookie(cookie)
+		req.Header.Set("X-CSRF-Token", token)
+		if strings.HasSuffix(tt.origin, "/form") {
+			req.Header.Set("Referer", tt.origin)
+		} else {
+			req.Header.Set("Origin", tt.origin)
+		}
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, tt.code, res.Code, tt.origin)
+	}
+}
+
+func TestCSRF_Exempt(t *testing.T) {
+	app := potgo.New()
+	app.Use(CSRF(CSRFConfig{Exempt: []string{"webhook"}}))
+
+	app.POST("/submit", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+	app.POST("/webhook", func(c *potgo.Context) error {
+		return c.Text("ok")
+	}).Name("webhook")
+
+	req, _ := http.NewRequest("POST", "/webhook", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "ok", res.Body.String())
+
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
+
+func TestCSRF_View(t *testing.T) {
+	app := potgo.New()
+	app.Use(CSRF())
+	_ = app.RegisterView(potgo.HTML("../testdata/views_3", ".html"))
+
+	app.GET("/view", func(c *potgo.Context) error {
+		return c.View("csrf.html")
+	})
+
+	req, _ := http.NewRequest("GET", "/view", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Contains(t, res.Body.String(), `<form><input type="hidden" name="_csrf" value="`)
+}
+
+func TestCSRF_Synchronizer(t *testing.T) {
+	store := sessions.NewMemoryStore()
+	defer store.Close()
+
+	app := potgo.New()
+	app.Use(sessions.Middleware(store), CSRF(CSRFConfig{Mode: CSRFSynchronizer}))
+	app.GET("/form", func(c *potgo.Context) error {
+		return c.Text(c.CSRFToken())
+	})
+	app.POST("/submit", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	cookie, token := getCSRFToken(app)
+	assert.Equal(t, sessions.DefaultOptions.Name, cookie.Name)
+
+	req, _ := http.NewRequest("POST", "/submit", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-CSRF-Token", token)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "ok", res.Body.String())
+
+	req, _ = http.NewRequest("POST", "/submit", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
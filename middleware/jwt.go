@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/icodechef/potgo"
+)
+
+// JWTClaimsKey JWT 中间件使用 Context.Set 保存声明使用的键，值的类型为 JWTClaims
+const JWTClaimsKey = "middleware.jwt.claims"
+
+var (
+	errJWTMalformed   = errors.New("malformed token")
+	errJWTAlgorithm   = errors.New("unsupported signing algorithm")
+	errJWTKey         = errors.New("unknown key")
+	errJWTSignature   = errors.New("invalid signature")
+	errJWTExpired     = errors.New("token is expired")
+	errJWTNotValidYet = errors.New("token is not valid yet")
+	errJWTTime        = errors.New("invalid exp or nbf claim")
+	errJWTAudience    = errors.New("invalid audience")
+	errJWTIssuer      = errors.New("invalid issuer")
+)
+
+// JWTClaims JWT 的声明
+type JWTClaims map[string]interface{}
+
+// Subject 返回 sub 声明
+func (claims JWTClaims) Subject() string {
+	s, _ := claims["sub"].(string)
+	return s
+}
+
+// JWTConfig JWT 中间件配置，Secret、PublicKey 和 JWKSFile 至少设置一个
+type JWTConfig struct {
+	// Secret HS256 使用的密钥，不能为空
+	Secret []byte
+	// PublicKey RS256 使用的 *rsa.PublicKey 或 ES256 使用的 *ecdsa.PublicKey
+	PublicKey crypto.PublicKey
+	// JWKSFile 本地 JWKS 文件，根据令牌头部的 kid 选择公钥
+	JWKSFile string
+	// Audience 不为空时检查 aud 声明
+	Audience string
+	// Issuer 不为空时检查 iss 声明
+	Issuer string
+	// Leeway 检查 exp 和 nbf 时允许的时钟偏差
+	Leeway time.Duration
+	// Realm 认证域，默认为 Restricted
+	Realm string
+}
+
+// jwk JWKS 中的一个密钥
+type jwk struct {
+	kid string
+	key interface{} // []byte、*rsa.PublicKey 或 *ecdsa.PublicKey
+}
+
+// JWT 返回 JWT 认证中间件，支持 HS256、RS256 和 ES256 签名算法
+//
+// 从 Authorization: Bearer 请求头中读取令牌，验证签名以及 exp、nbf、aud、iss 声明，
+// 验证成功后使用 Context.Set 将声明保存在 JWTClaimsKey 中，sub 声明保存在 AuthUserKey 中。
+// 验证失败时设置 WWW-Authenticate 响应头并返回 401 错误
+func JWT(config JWTConfig) potgo.HandlerFunc {
+	var keys []jwk
+	if config.Secret != nil {
+		if len(config.Secret) == 0 {
+			panic("middleware: JWT Secret must not be empty")
+		}
+		keys = append(keys, jwk{key: config.Secret})
+	}
+	if config.PublicKey != nil {
+		keys = append(keys, jwk{key: config.PublicKey})
+	}
+	if config.JWKSFile != "" {
+		jwks, err := loadJWKS(config.JWKSFile)
+		if err != nil {
+			panic(fmt.Sprintf("middleware: load JWKS file [%s] err: %v", config.JWKSFile, err))
+		}
+		keys = append(keys, jwks...)
+	}
+	if len(keys) == 0 {
+		panic("middleware: JWT requires Secret, PublicKey or JWKSFile")
+	}
+
+	challenge := "Bearer realm=" + strconv.Quote(authRealm([]string{config.Realm}))
+
+	return func(c *potgo.Context) error {
+		token := authToken(c.Request, "Authorization", "Bearer")
+		if token == "" {
+			return unauthorized(c, challenge)
+		}
+
+		claims, err := parseJWT(token, keys, &config, time.Now())
+		if err != nil {
+			return unauthorized(c, challenge+`, error="invalid_token", error_description=`+strconv.Quote(err.Error()))
+		}
+
+		c.Set(JWTClaimsKey, claims)
+		if sub := claims.Subject(); sub != "" {
+			c.Set(AuthUserKey, sub)
+		}
+		return c.Next()
+	}
+}
+
+// parseJWT 验证令牌并返回声明
+func parseJWT(token string, keys []jwk, config *JWTConfig, now time.Time) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errJWTMalformed
+	}
+
+	if err := verifyJWT(header.Alg, header.Kid, parts[0]+"."+parts[1], sig, keys); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, validateJWTClaims(claims, config, now)
+}
+
+func decodeJWTPart(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errJWTMalformed
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return errJWTMalformed
+	}
+	return nil
+}
+
+// verifyJWT 使用与算法匹配的密钥验证签名，密钥的类型决定了可以使用的算法，防止算法混淆攻击
+func verifyJWT(alg, kid, signingInput string, sig []byte, keys []jwk) error {
+	hash := sha256.Sum256([]byte(signingInput))
+	found, matched := false, false
+
+	for _, k := range keys {
+		if kid != "" && k.kid != "" && k.kid != kid {
+			continue
+		}
+		found = true
+
+		switch key := k.key.(type) {
+		case []byte:
+			if alg != "HS256" {
+				continue
+			}
+			matched = true
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(signingInput))
+			if hmac.Equal(sig, mac.Sum(nil)) {
+				return nil
+			}
+		case *rsa.PublicKey:
+			if alg != "RS256" {
+				continue
+			}
+			matched = true
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			if alg != "ES256" || key.Curve != elliptic.P256() {
+				continue
+			}
+			matched = true
+			if len(sig) == 64 {
+				r := new(big.Int).SetBytes(sig[:32])
+				s := new(big.Int).SetBytes(sig[32:])
+				if ecdsa.Verify(key, hash[:], r, s) {
+					return nil
+				}
+			}
+		}
+	}
+
+	if !found {
+		return errJWTKey
+	}
+	if !matched {
+		return errJWTAlgorithm
+	}
+	return errJWTSignature
+}
+
+func validateJWTClaims(claims JWTClaims, config *JWTConfig, now time.Time) error {
+	if v, ok := claims["exp"]; ok {
+		exp, err := jwtTime(v)
+		if err != nil {
+			return err
+		}
+		if !now.Before(exp.Add(config.Leeway)) {
+			return errJWTExpired
+		}
+	}
+	if v, ok := claims["nbf"]; ok {
+		nbf, err := jwtTime(v)
+		if err != nil {
+			return err
+		}
+		if now.Add(config.Leeway).Before(nbf) {
+			return errJWTNotValidYet
+		}
+	}
+
+	if config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != config.Issuer {
+			return errJWTIssuer
+		}
+	}
+
+	if config.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == config.Audience
+		case []interface{}:
+			for _, a := range aud {
+				if s, _ := a.(string); s == config.Audience {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return errJWTAudience
+		}
+	}
+	return nil
+}
+
+// jwtTime 将 exp 或 nbf 声明转换为时间，声明不是数字时返回错误
+func jwtTime(v interface{}) (time.Time, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, errJWTTime
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, errJWTTime
+	}
+	return time.Unix(int64(f), 0), nil
+}
+
+// loadJWKS 读取本地 JWKS 文件，支持 RSA、EC(P-256) 和 oct 类型的密钥
+func loadJWKS(file string) ([]jwk, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make([]jwk, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid RSA key %q", k.Kid)
+			}
+			keys = append(keys, jwk{kid: k.Kid, key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}})
+		case "EC":
+			if k.Crv != "P-256" {
+				return nil, fmt.Errorf("unsupported curve %q of key %q", k.Crv, k.Kid)
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+			}
+			keys = append(keys, jwk{kid: k.Kid, key: &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}})
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("invalid oct key %q", k.Kid)
+			}
+			keys = append(keys, jwk{kid: k.Kid, key: secret})
+		default:
+			return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	h, _ := json.Marshal(header)
+	p, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+	hash := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hash[:])
+		assert.Nil(t, err)
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+		assert.Nil(t, err)
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwtRequest(app *potgo.Application, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestJWT_HS256(t *testing.T) {
+	secret := []byte("secret")
+	app := potgo.New()
+	app.Use(JWT(JWTConfig{Secret: secret, Issuer: "potgo", Audience: "api", Leeway: time.Minute}))
+	app.GET("/", func(c *potgo.Context) error {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		return c.Text("%s:%s", c.MustGet(AuthUserKey), claims["role"])
+	})
+
+	header := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+	now := time.Now().Unix()
+
+	res := jwtRequest(app, "")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Equal(t, `Bearer realm="Restricted"`, res.Header().Get("WWW-Authenticate"))
+
+	token := signJWT(t, header, map[string]interface{}{
+		"sub": "42", "role": "admin", "iss": "potgo", "aud": []string{"web", "api"}, "exp": now + 60,
+	}, secret)
+	res = jwtRequest(app, token)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "42:admin", res.Body.String())
+
+	tests := []struct {
+		claims      map[string]interface{}
+		key         []byte
+		description string
+	}{
+		{map[string]interface{}{"iss": "potgo", "aud": "api"}, []byte("wrong"), "invalid signature"},
+		{map[string]interface{}{"iss": "potgo", "aud": "api", "exp": now - 120}, secret, "token is expired"},
+		{map[string]interface{}{"iss": "potgo", "aud": "api", "nbf": now + 120}, secret, "token is not valid yet"},
+		{map[string]interface{}{"iss": "other", "aud": "api"}, secret, "invalid issuer"},
+		{map[string]interface{}{"iss": "potgo", "aud": "web"}, secret, "invalid audience"},
+		{map[string]interface{}{"iss": "potgo", "aud": "api", "exp": "never"}, secret, "invalid exp or nbf claim"},
+		{map[string]interface{}{"iss": "potgo", "aud": "api", "nbf": nil}, secret, "invalid exp or nbf claim"},
+	}
+	for _, tt := range tests {
+		res = jwtRequest(app, signJWT(t, header, tt.claims, tt.key))
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Equal(t, `Bearer realm="Restricted", error="invalid_token", error_description="`+tt.description+`"`,
+			res.Header().Get("WWW-Authenticate"))
+	}
+
+	// 在允许的时钟偏差之内
+	res = jwtRequest(app, signJWT(t, header, map[string]interface{}{
+		"sub": "42", "iss": "potgo", "aud": "api", "exp": now - 30,
+	}, secret))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	res = jwtRequest(app, "a.b")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Contains(t, res.Header().Get("WWW-Authenticate"), "malformed token")
+
+	// 不支持的算法
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{}, secret))
+	assert.Contains(t, res.Header().Get("WWW-Authenticate"), "unsupported signing algorithm")
+}
+
+func TestJWT_EmptySecret(t *testing.T) {
+	assert.Panics(t, func() {
+		JWT(JWTConfig{})
+	})
+	assert.Panics(t, func() {
+		JWT(JWTConfig{Secret: []byte("")})
+	})
+
+	// 同时设置了其它密钥时也不能使用空的 Secret
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	assert.Panics(t, func() {
+		JWT(JWTConfig{Secret: []byte{}, PublicKey: &key.PublicKey})
+	})
+}
+
+func TestJWT_RS256AndES256(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	claims := map[string]interface{}{"sub": "7", "role": "user", "exp": time.Now().Add(time.Hour).Unix()}
+
+	app := potgo.New()
+	app.Use(JWT(JWTConfig{PublicKey: &rsaKey.PublicKey}))
+	app.GET("/", func(c *potgo.Context) error {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		return c.Text("%s:%s", c.MustGet(AuthUserKey), claims["role"])
+	})
+
+	res := jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "RS256"}, claims, rsaKey))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "7:user", res.Body.String())
+
+	// 公钥不能作为 HS256 的密钥使用
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "HS256"}, claims, []byte("secret")))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	app = potgo.New()
+	app.Use(JWT(JWTConfig{PublicKey: &ecKey.PublicKey}))
+	app.GET("/", func(c *potgo.Context) error {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		return c.Text("%s:%s", c.MustGet(AuthUserKey), claims["role"])
+	})
+
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "ES256"}, claims, ecKey))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "ES256"}, claims, other))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestJWT_JWKSFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	b64 := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+			{"kty": "oct", "kid": "hmac-1", "k": b64([]byte("secret"))},
+		},
+	})
+
+	dir, err := ioutil.TempDir("", "potgo-jwks")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "jwks.json")
+	assert.Nil(t, ioutil.WriteFile(file, jwks, 0600))
+
+	app := potgo.New()
+	app.Use(JWT(JWTConfig{JWKSFile: file}))
+	app.GET("/", func(c *potgo.Context) error {
+		claims := c.MustGet(JWTClaimsKey).(JWTClaims)
+		return c.Text("%s:%s", c.MustGet(AuthUserKey), claims["role"])
+	})
+
+	claims := map[string]interface{}{"sub": "1", "role": "guest"}
+
+	res := jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "rsa-1"}, claims, rsaKey))
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "ES256", "kid": "ec-1"}, claims, ecKey))
+	assert.Equal(t, http.StatusOK, res.Code)
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "HS256", "kid": "hmac-1"}, claims, []byte("secret")))
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	// kid 不匹配
+	res = jwtRequest(app, signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "unknown"}, claims, rsaKey))
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+	assert.Contains(t, res.Header().Get("WWW-Authenticate"), "unknown key")
+
+	assert.Panics(t, func() {
+		JWT(JWTConfig{JWKSFile: filepath.Join(dir, "missing.json")})
+	})
+	assert.Panics(t, func() {
+		JWT(JWTConfig{})
+	})
+}
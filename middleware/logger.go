@@ -1,15 +1,85 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/icodechef/potgo"
 )
 
+// LogFormat 访问日志格式
+type LogFormat int
+
+const (
+	// LogText 默认的文本格式
+	LogText LogFormat = iota
+	// LogJSON 每行一个 JSON 对象
+	LogJSON
+	// LogLogfmt key=value 格式
+	LogLogfmt
+	// LogCombined Apache combined 格式
+	LogCombined
+)
+
+// LoggerConfig 日志中间件配置
+type LoggerConfig struct {
+	// Output 日志输出，默认为 os.Stdout
+	Output io.Writer
+	// Format 日志格式，默认为 LogText
+	Format LogFormat
+	// Template 自定义日志模板，不为空时忽略 Format，例如 "${time} ${status} ${method} ${path}"
+	//
+	// 可用的字段：time、client_ip、host、method、path、query、uri、proto、status、latency、latency_ms、
+	// bytes_in、bytes_out、route、route_name、user_agent、referer、user、request_id、trace_id、span_id、error、slow，
+	// 以及 ${header:Name} 读取请求头
+	Template string
+	// TimeFormat 时间格式，默认为 2006/01/02 - 15:04:05，LogJSON 和 LogLogfmt 为 RFC3339
+	TimeFormat string
+	// SkipPaths 不记录日志的请求路径
+	SkipPaths []string
+	// Skip 返回 true 时不记录日志
+	Skip func(c *potgo.Context) bool
+	// MinLatency 只记录耗时不小于 MinLatency 的请求
+	MinLatency time.Duration
+	// SlowThreshold 耗时超过 SlowThreshold 的请求标记为慢请求
+	SlowThreshold time.Duration
+}
+
+// logEntry 一条访问日志
+type logEntry struct {
+	time      time.Time
+	clientIP  string
+	host      string
+	method    string
+	path      string
+	query     string
+	proto     string
+	status    int
+	latency   time.Duration
+	bytesIn   int64
+	bytesOut  int
+	route     string
+	routeName string
+	userAgent string
+	referer   string
+	user      string
+	requestID string
+	traceID   string
+	spanID    string
+	err       string
+	slow      bool
+	header    http.Header
+}
+
 // Logger 返回日志中间件
 func Logger() potgo.HandlerFunc {
 	return LoggerWithWriter(os.Stdout)
@@ -17,36 +87,413 @@ func Logger() potgo.HandlerFunc {
 
 // LoggerWithWriter 返回指定 io.Writer 的日志中间件
 func LoggerWithWriter(out io.Writer) potgo.HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Output: out})
+}
+
+// LoggerWithFile 返回以追加方式写入文件的日志中间件，可以指定文件的轮转规则
+//
+// 打开文件失败时记录错误并写入 os.Stderr，需要处理错误时使用 NewRotateFile 和 LoggerWithConfig
+func LoggerWithFile(file string, rotate ...RotateConfig) potgo.HandlerFunc {
+	f, err := NewRotateFile(file, rotate...)
+	if err != nil {
+		log.Printf("middleware: open log file [%s] err: %v, writing access log to stderr", file, err)
+		return LoggerWithWriter(os.Stderr)
+	}
+
+	return LoggerWithWriter(f)
+}
+
+// LoggerWithConfig 返回指定配置的日志中间件
+//
+//	app.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+//		Output:        file,
+//		Format:        middleware.LogJSON,
+//		SkipPaths:     []string{"/health"},
+//		SlowThreshold: time.Second,
+//	}))
+func LoggerWithConfig(config LoggerConfig) potgo.HandlerFunc {
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+	if config.TimeFormat == "" {
+		switch config.Format {
+		case LogJSON, LogLogfmt:
+			config.TimeFormat = time.RFC3339
+		case LogCombined:
+			config.TimeFormat = "02/Jan/2006:15:04:05 -0700"
+		default:
+			config.TimeFormat = "2006/01/02 - 15:04:05"
+		}
+	}
+
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, p := range config.SkipPaths {
+		skip[p] = true
+	}
+
+	var format func(buf *bytes.Buffer, e *logEntry)
+	if config.Template != "" {
+		format = compileLogTemplate(config.Template, config.TimeFormat)
+	} else {
+		switch config.Format {
+		case LogJSON:
+			format = func(buf *bytes.Buffer, e *logEntry) { formatJSON(buf, e, config.TimeFormat) }
+		case LogLogfmt:
+			format = func(buf *bytes.Buffer, e *logEntry) { formatLogfmt(buf, e, config.TimeFormat) }
+		case LogCombined:
+			format = func(buf *bytes.Buffer, e *logEntry) { formatCombined(buf, e, config.TimeFormat) }
+		default:
+			format = func(buf *bytes.Buffer, e *logEntry) { formatText(buf, e, config.TimeFormat) }
+		}
+	}
+
+	var mu sync.Mutex
+
 	return func(c *potgo.Context) error {
+		if skip[c.Request.URL.Path] || (config.Skip != nil && config.Skip(c)) {
+			return c.Next()
+		}
+
 		start := time.Now()
+
+		var body *countingReader
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body = &countingReader{ReadCloser: c.Request.Body}
+			c.Request.Body = body
+		}
+
 		err := c.Next()
-		clientIP := c.ClientIP()
-		elapsed := time.Now().Sub(start)
 
-		if elapsed > time.Minute {
-			elapsed = elapsed - elapsed%time.Second
+		latency := time.Since(start)
+		if latency < config.MinLatency {
+			return err
 		}
 
-		fmt.Fprintf(out, "%v | %15s | %13v | %s %s %d %#v \n",
-			time.Now().Format("2006/01/02 - 15:04:05"),
-			clientIP,
-			elapsed,
-			c.Request.Method,
-			c.Request.Proto,
-			c.Response.Status(),
-			c.Request.URL.String())
+		e := newLogEntry(c, err)
+		e.time = start
+		e.latency = latency
+		e.slow = config.SlowThreshold > 0 && latency >= config.SlowThreshold
+		if body != nil {
+			e.bytesIn = body.n
+			c.Request.Body = body.ReadCloser
+		}
+
+		buf := new(bytes.Buffer)
+		format(buf, e)
+		buf.WriteByte('\n')
+
+		mu.Lock()
+		_, _ = config.Output.Write(buf.Bytes())
+		mu.Unlock()
 
 		return err
 	}
 }
 
-// LoggerWithFile 返回写入文件的日志中间件
-func LoggerWithFile(file string) potgo.HandlerFunc {
-	f, err := os.Create(file)
+func newLogEntry(c *potgo.Context, err error) *logEntry {
+	req := c.Request
+	e := &logEntry{
+		clientIP:  c.ClientIP(),
+		host:      req.Host,
+		method:    req.Method,
+		path:      req.URL.EscapedPath(),
+		query:     req.URL.RawQuery,
+		proto:     req.Proto,
+		status:    c.Response.Status(),
+		bytesOut:  c.Response.Size(),
+		userAgent: req.UserAgent(),
+		referer:   req.Referer(),
+		requestID: c.RequestID(),
+		header:    req.Header,
+	}
+
+	if e.bytesOut < 0 {
+		e.bytesOut = 0
+	}
 
+	// 返回的错误由 ErrorHandlerFunc 在之后处理，此时还没有写入响应
 	if err != nil {
-		log.Fatalf("LoggerWithFile: open log file [%s] err: %v", file, err)
+		e.err = err.Error()
+		if !c.Response.Written() {
+			e.status = http.StatusInternalServerError
+			if he, ok := err.(potgo.HTTPError); ok {
+				e.status = he.Status()
+			}
+		}
 	}
 
-	return LoggerWithWriter(f)
+	if r := c.Route(); r != nil {
+		e.route = r.Path()
+		e.routeName = r.GetName()
+	}
+	if tc := c.TraceContext(); tc != nil {
+		e.traceID = tc.TraceID
+		e.spanID = tc.SpanID
+	}
+	if user, ok := c.Get(AuthUserKey); ok {
+		e.user, _ = user.(string)
+	}
+	return e
+}
+
+// uri 返回转义后的请求路径和查询字符串
+func (e *logEntry) uri() string {
+	if e.query == "" {
+		return e.path
+	}
+	return e.path + "?" + e.query
+}
+
+// field 返回模板中字段的值
+func (e *logEntry) field(name, timeFormat string) string {
+	switch name {
+	case "time":
+		return e.time.Format(timeFormat)
+	case "client_ip":
+		return e.clientIP
+	case "host":
+		return e.host
+	case "method":
+		return e.method
+	case "path":
+		return e.path
+	case "uri":
+		return e.uri()
+	case "query":
+		return e.query
+	case "proto":
+		return e.proto
+	case "status":
+		return strconv.Itoa(e.status)
+	case "latency":
+		return e.latency.String()
+	case "latency_ms":
+		return strconv.FormatFloat(float64(e.latency)/float64(time.Millisecond), 'f', 3, 64)
+	case "bytes_in":
+		return strconv.FormatInt(e.bytesIn, 10)
+	case "bytes_out":
+		return strconv.Itoa(e.bytesOut)
+	case "route":
+		return e.route
+	case "route_name":
+		return e.routeName
+	case "user_agent":
+		return e.userAgent
+	case "referer":
+		return e.referer
+	case "user":
+		return e.user
+	case "request_id":
+		return e.requestID
+	case "trace_id":
+		return e.traceID
+	case "span_id":
+		return e.spanID
+	case "error":
+		return e.err
+	case "slow":
+		return strconv.FormatBool(e.slow)
+	}
+	if strings.HasPrefix(name, "header:") {
+		return e.header.Get(name[len("header:"):])
+	}
+	return ""
+}
+
+// compileLogTemplate 将 ${field} 模板预先拆分为文本和字段
+func compileLogTemplate(tpl, timeFormat string) func(buf *bytes.Buffer, e *logEntry) {
+	var texts, fields []string
+	for {
+		i := strings.Index(tpl, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(tpl[i:], '}')
+		if j < 0 {
+			break
+		}
+		texts = append(texts, tpl[:i])
+		fields = append(fields, tpl[i+2:i+j])
+		tpl = tpl[i+j+1:]
+	}
+	texts = append(texts, tpl)
+
+	return func(buf *bytes.Buffer, e *logEntry) {
+		for i, field := range fields {
+			buf.WriteString(texts[i])
+			buf.WriteString(sanitizeLogValue(e.field(field, timeFormat)))
+		}
+		buf.WriteString(texts[len(texts)-1])
+	}
+}
+
+func formatText(buf *bytes.Buffer, e *logEntry, timeFormat string) {
+	latency := e.latency
+	if latency > time.Minute {
+		latency = latency - latency%time.Second
+	}
+
+	fmt.Fprintf(buf, "%v | %15s | %13v | %s %s %d %s",
+		e.time.Format(timeFormat),
+		e.clientIP,
+		latency,
+		e.method,
+		e.proto,
+		e.status,
+		e.uri())
+
+	var extra string
+	if e.requestID != "" {
+		extra += " request_id=" + sanitizeLogValue(e.requestID)
+	}
+	if e.traceID != "" {
+		extra += " trace_id=" + e.traceID + " span_id=" + e.spanID
+	}
+	if e.slow {
+		extra += " slow"
+	}
+	if extra != "" {
+		buf.WriteString(" |" + extra)
+	}
+}
+
+func formatJSON(buf *bytes.Buffer, e *logEntry, timeFormat string) {
+	buf.WriteByte('{')
+	writeJSONField(buf, "time", e.time.Format(timeFormat))
+	writeJSONField(buf, "client_ip", e.clientIP)
+	writeJSONField(buf, "host", e.host)
+	writeJSONField(buf, "method", e.method)
+	writeJSONField(buf, "path", e.path)
+	writeJSONField(buf, "query", e.query)
+	writeJSONField(buf, "proto", e.proto)
+	writeJSONField(buf, "status", e.status)
+	writeJSONField(buf, "latency", e.latency.String())
+	writeJSONField(buf, "latency_ms", float64(e.latency)/float64(time.Millisecond))
+	writeJSONField(buf, "bytes_in", e.bytesIn)
+	writeJSONField(buf, "bytes_out", e.bytesOut)
+	writeJSONField(buf, "user_agent", e.userAgent)
+	writeJSONField(buf, "referer", e.referer)
+	for _, f := range [...][2]string{
+		{"route", e.route},
+		{"route_name", e.routeName},
+		{"user", e.user},
+		{"request_id", e.requestID},
+		{"trace_id", e.traceID},
+		{"span_id", e.spanID},
+		{"error", e.err},
+	} {
+		if f[1] != "" {
+			writeJSONField(buf, f[0], f[1])
+		}
+	}
+	if e.slow {
+		writeJSONField(buf, "slow", true)
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(strconv.Quote(key))
+	buf.WriteByte(':')
+	b, _ := json.Marshal(value)
+	buf.Write(b)
+}
+
+func formatLogfmt(buf *bytes.Buffer, e *logEntry, timeFormat string) {
+	pairs := [...][2]string{
+		{"time", e.time.Format(timeFormat)},
+		{"client_ip", e.clientIP},
+		{"host", e.host},
+		{"method", e.method},
+		{"path", e.path},
+		{"query", e.query},
+		{"proto", e.proto},
+		{"status", strconv.Itoa(e.status)},
+		{"latency", e.latency.String()},
+		{"bytes_in", strconv.FormatInt(e.bytesIn, 10)},
+		{"bytes_out", strconv.Itoa(e.bytesOut)},
+		{"user_agent", e.userAgent},
+		{"referer", e.referer},
+		{"route", e.route},
+		{"route_name", e.routeName},
+		{"user", e.user},
+		{"request_id", e.requestID},
+		{"trace_id", e.traceID},
+		{"span_id", e.spanID},
+		{"error", e.err},
+	}
+	for _, p := range pairs {
+		if p[1] == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(p[0])
+		buf.WriteByte('=')
+		if strings.ContainsAny(p[1], " =\"\\") || strings.IndexFunc(p[1], isLogControl) >= 0 {
+			buf.WriteString(strconv.Quote(p[1]))
+		} else {
+			buf.WriteString(p[1])
+		}
+	}
+	if e.slow {
+		buf.WriteString(" slow=true")
+	}
+}
+
+// formatCombined Apache combined 格式：%h - %u [%t] "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+func formatCombined(buf *bytes.Buffer, e *logEntry, timeFormat string) {
+	user := "-"
+	if e.user != "" {
+		user = sanitizeLogValue(e.user)
+	}
+	size := "-"
+	if e.bytesOut > 0 {
+		size = strconv.Itoa(e.bytesOut)
+	}
+	fmt.Fprintf(buf, "%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"",
+		e.clientIP,
+		user,
+		e.time.Format(timeFormat),
+		e.method,
+		e.uri(),
+		e.proto,
+		e.status,
+		size,
+		combinedEscape(e.referer),
+		combinedEscape(e.userAgent))
+}
+
+func combinedEscape(s string) string {
+	s = sanitizeLogValue(s)
+	return strings.Replace(s, `"`, `\"`, -1)
+}
+
+// sanitizeLogValue 转义控制字符，防止日志注入
+func sanitizeLogValue(s string) string {
+	if strings.IndexFunc(s, isLogControl) < 0 {
+		return s
+	}
+	q := strconv.Quote(s)
+	return q[1 : len(q)-1]
+}
+
+func isLogControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// countingReader 统计读取的请求体大小
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
 }
@@ -2,11 +2,18 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"github.com/icodechef/potgo"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLogger(t *testing.T) {
@@ -26,3 +33,174 @@ func TestLogger(t *testing.T) {
 	assert.Contains(t, buf.String(), "GET")
 	assert.Contains(t, buf.String(), "/test")
 }
+
+func TestLogger_RequestID(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	app := potgo.New()
+	app.Use(LoggerWithWriter(buf), RequestID())
+	app.GET("/test", func(c *potgo.Context) error {
+		return nil
+	})
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.ServeHTTP(res, req)
+
+	assert.Contains(t, buf.String(), "request_id=abc-123")
+	assert.Contains(t, buf.String(), "trace_id=4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestLoggerWithConfig_JSON(t *testing.T) {
+	buf := new(bytes.Buffer)
+	app := potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, Format: LogJSON, SkipPaths: []string{"/health"}}))
+	app.POST("/users/{id}", func(c *potgo.Context) error {
+		if c.Request.Body != nil {
+			_, _ = ioutil.ReadAll(c.Request.Body)
+		}
+		return c.Text("hello")
+	}).Name("user")
+	app.GET("/error", func(c *potgo.Context) error {
+		return potgo.NewHTTPError(http.StatusForbidden)
+	})
+	app.GET("/health", func(c *potgo.Context) error {
+		return nil
+	})
+
+	req, _ := http.NewRequest("POST", "/users/1?a=b", strings.NewReader("name=foo"))
+	req.Header.Set("User-Agent", "test")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/users/1", entry["path"])
+	assert.Equal(t, "a=b", entry["query"])
+	assert.Equal(t, float64(200), entry["status"])
+	assert.Equal(t, float64(8), entry["bytes_in"])
+	assert.Equal(t, float64(5), entry["bytes_out"])
+	assert.Equal(t, "/users/:id", entry["route"])
+	assert.Equal(t, "user", entry["route_name"])
+	assert.Equal(t, "test", entry["user_agent"])
+
+	buf.Reset()
+	req, _ = http.NewRequest("GET", "/health", nil)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "", buf.String())
+
+	// 返回错误时记录错误的状态码
+	req, _ = http.NewRequest("GET", "/error", nil)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(403), entry["status"])
+	assert.Equal(t, "Forbidden", entry["error"])
+}
+
+func TestLoggerWithConfig_Formats(t *testing.T) {
+	buf := new(bytes.Buffer)
+	app := potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, Format: LogLogfmt}))
+	app.POST("/users/{id}", func(c *potgo.Context) error {
+		if c.Request.Body != nil {
+			_, _ = ioutil.ReadAll(c.Request.Body)
+		}
+		return c.Text("hello")
+	}).Name("user")
+
+	req, _ := http.NewRequest("POST", "/users/1", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11)")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Contains(t, buf.String(), "method=POST path=/users/1 ")
+	assert.Contains(t, buf.String(), "status=200")
+	assert.Contains(t, buf.String(), `user_agent="Mozilla/5.0 (X11)"`)
+	assert.Contains(t, buf.String(), "route=/users/:id")
+
+	buf.Reset()
+	app = potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, Format: LogCombined}))
+	app.POST("/users/{id}", func(c *potgo.Context) error {
+		if c.Request.Body != nil {
+			_, _ = ioutil.ReadAll(c.Request.Body)
+		}
+		return c.Text("hello")
+	}).Name("user")
+
+	req, _ = http.NewRequest("POST", "/users/1?a=b", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", "curl")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Regexp(t, `^10\.0\.0\.1 - - \[.+\] "POST /users/1\?a=b HTTP/1\.1" 200 5 "http://example\.com/" "curl"\n$`, buf.String())
+
+	buf.Reset()
+	app = potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, Template: "${method} ${uri} ${status} ${header:X-Foo} ${unknown}|"}))
+	app.POST("/users/{id}", func(c *potgo.Context) error {
+		if c.Request.Body != nil {
+			_, _ = ioutil.ReadAll(c.Request.Body)
+		}
+		return c.Text("hello")
+	}).Name("user")
+
+	req, _ = http.NewRequest("POST", "/users/1?a=b", nil)
+	req.Header.Set("X-Foo", "bar\nbaz")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "POST /users/1?a=b 200 bar\\nbaz |\n", buf.String())
+}
+
+func TestLoggerWithConfig_Latency(t *testing.T) {
+	buf := new(bytes.Buffer)
+	app := potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, MinLatency: time.Hour}))
+	app.GET("/health", func(c *potgo.Context) error {
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "", buf.String())
+
+	app = potgo.New()
+	app.Use(LoggerWithConfig(LoggerConfig{Output: buf, SlowThreshold: time.Nanosecond}))
+	app.GET("/health", func(c *potgo.Context) error {
+		return nil
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Contains(t, buf.String(), " slow\n")
+}
+
+func TestLoggerWithFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "potgo-logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "access.log")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("old\n"), 0644))
+
+	app := potgo.New()
+	app.Use(LoggerWithFile(file))
+	app.GET("/test", func(c *potgo.Context) error {
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	// 以追加方式写入
+	b, _ := ioutil.ReadFile(file)
+	assert.True(t, strings.HasPrefix(string(b), "old\n"))
+	assert.Contains(t, string(b), "/test")
+
+	// 打开文件失败时不 panic，记录错误并写入 os.Stderr
+	logBuf := new(bytes.Buffer)
+	log.SetOutput(logBuf)
+	defer log.SetOutput(os.Stderr)
+	assert.NotPanics(t, func() {
+		assert.NotNil(t, LoggerWithFile(filepath.Join(file, "invalid.log")))
+	})
+	assert.Contains(t, logBuf.String(), "invalid.log")
+}
@@ -0,0 +1,351 @@
+package middleware
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/icodechef/potgo"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm uint8
+
+const (
+	// TokenBucket 令牌桶，允许一定的突发请求，令牌以 Limit/Window 的速率恢复
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow 滑动窗口，使用前一个窗口的计数按比例估算当前窗口内的请求数
+	SlidingWindow
+)
+
+// RateLimitResult 一次请求的限流结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 配额完全恢复需要的时间
+	RetryAfter time.Duration // 请求被拒绝时需要等待的时间
+}
+
+// RateLimitStore 限流数据存储接口，实现必须是并发安全的
+type RateLimitStore interface {
+	// Take 为 key 消耗一次请求配额
+	Take(key string, algorithm RateLimitAlgorithm, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitKeyFunc 返回限流使用的键，返回空字符串时不限流
+type RateLimitKeyFunc func(c *potgo.Context) string
+
+// RateLimitConfig 限流中间件配置
+type RateLimitConfig struct {
+	Algorithm RateLimitAlgorithm // 限流算法，默认为 TokenBucket
+	Limit     int                // 每个窗口允许的请求数
+	Window    time.Duration      // 窗口大小，默认为 1 分钟
+	Store     RateLimitStore     // 默认为不需要 Close 的内存存储
+	KeyFunc   RateLimitKeyFunc   // 默认为 KeyByClientIP()
+}
+
+// RateLimit 返回限流中间件
+//
+// 没有设置 Store 时使用的内存存储在请求时清理过期数据，不会启动 goroutine。
+// 响应中包含 RateLimit-Limit、RateLimit-Remaining 和 RateLimit-Reset 响应头，
+// 超出限制时设置 Retry-After 响应头并返回 429 错误，交给应用的错误处理程序处理
+func RateLimit(config RateLimitConfig) potgo.HandlerFunc {
+	if config.Limit <= 0 {
+		panic("middleware: rate limit must be greater than 0")
+	}
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Store == nil {
+		config.Store = newMemoryRateLimitStore(32, false)
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = KeyByClientIP()
+	}
+
+	return func(c *potgo.Context) error {
+		key := config.KeyFunc(c)
+		if key == "" {
+			return c.Next()
+		}
+
+		result, err := config.Store.Take(key, config.Algorithm, config.Limit, config.Window)
+		if err != nil {
+			return err
+		}
+
+		header := c.Response.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			return potgo.NewHTTPError(http.StatusTooManyRequests)
+		}
+		return c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// KeyByClientIP 使用客户端 IP 作为限流的键
+func KeyByClientIP() RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		return c.ClientIP()
+	}
+}
+
+// KeyByHeader 使用请求头作为限流的键，例如 API 密钥
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		return c.Request.Header.Get(name)
+	}
+}
+
+// KeyByRoute 使用匹配的路由名称作为限流的键，路由没有命名时使用路由的路径模板
+func KeyByRoute() RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		r := c.Route()
+		if r == nil {
+			return ""
+		}
+		if name := r.GetName(); name != "" {
+			return name
+		}
+		return r.Method() + " " + r.Path()
+	}
+}
+
+// KeyByContext 使用上下文中使用字符串键保存的数据作为限流的键，例如认证中间件保存在 AuthUserKey 中的用户名，
+// 使用 potgo.Key 保存的数据使用 KeyByKey
+func KeyByContext(key string) RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		if value, ok := c.Get(key); ok && value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+}
+
+// KeyByKey 使用上下文中使用 potgo.Key 保存的数据作为限流的键
+func KeyByKey(key *potgo.Key) RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		if value, ok := key.Get(c); ok && value != nil {
+			return fmt.Sprint(value)
+		}
+		return ""
+	}
+}
+
+// KeyJoin 组合多个键，例如按路由和客户端 IP 限流，任意一个键为空时不限流
+func KeyJoin(fns ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(c *potgo.Context) string {
+		keys := make([]string, len(fns))
+		for i, fn := range fns {
+			if keys[i] = fn(c); keys[i] == "" {
+				return ""
+			}
+		}
+		return strings.Join(keys, "|")
+	}
+}
+
+// MemoryRateLimitStore 内存限流存储，数据分片保存以减少锁竞争，过期的数据会定期清理
+type MemoryRateLimitStore struct {
+	shards []*rateLimitShard
+	lazy   bool // 是否在 Take 时清理过期数据，而不是使用 goroutine 定期清理
+	done   chan struct{}
+	once   sync.Once
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	nextSweep time.Time
+}
+
+// rateLimitSweepInterval 清理过期数据的间隔
+const rateLimitSweepInterval = time.Minute
+
+type rateLimitEntry struct {
+	// 令牌桶
+	tokens float64
+	last   time.Time
+	// 滑动窗口
+	start   time.Time
+	prev    int
+	curr    int
+	expires time.Time
+}
+
+var _ RateLimitStore = &MemoryRateLimitStore{}
+
+// NewMemoryRateLimitStore 创建内存限流存储，shards 为分片数量，默认为 32
+//
+// 存储启动 goroutine 定期清理过期数据，不再使用时调用 Close 停止
+func NewMemoryRateLimitStore(shards ...int) *MemoryRateLimitStore {
+	n := 32
+	if len(shards) > 0 && shards[0] > 0 {
+		n = shards[0]
+	}
+	return newMemoryRateLimitStore(n, true)
+}
+
+// newMemoryRateLimitStore sweep 为 false 时不启动 goroutine，在 Take 时清理所在分片的过期数据
+func newMemoryRateLimitStore(n int, sweep bool) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		shards: make([]*rateLimitShard, n),
+		lazy:   !sweep,
+		done:   make(chan struct{}),
+	}
+	now := time.Now()
+	for i := range s.shards {
+		s.shards[i] = &rateLimitShard{
+			entries:   make(map[string]*rateLimitEntry),
+			nextSweep: now.Add(rateLimitSweepInterval),
+		}
+	}
+	if sweep {
+		go s.sweep(rateLimitSweepInterval)
+	}
+
+	return s
+}
+
+// Take 为 key 消耗一次请求配额
+func (s *MemoryRateLimitStore) Take(key string, algorithm RateLimitAlgorithm, limit int, window time.Duration) (RateLimitResult, error) {
+	shard := s.shard(key)
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if s.lazy && now.After(shard.nextSweep) {
+		shard.removeExpired(now)
+		shard.nextSweep = now.Add(rateLimitSweepInterval)
+	}
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(limit), last: now, start: now}
+		shard.entries[key] = e
+	}
+
+	var result RateLimitResult
+	switch algorithm {
+	case SlidingWindow:
+		result = e.slidingWindow(now, limit, window)
+	default:
+		result = e.tokenBucket(now, limit, window)
+	}
+	e.expires = now.Add(2 * window)
+	return result, nil
+}
+
+// Close 停止清理过期数据
+func (s *MemoryRateLimitStore) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *MemoryRateLimitStore) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *MemoryRateLimitStore) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			for _, shard := range s.shards {
+				shard.mu.Lock()
+				shard.removeExpired(now)
+				shard.mu.Unlock()
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// removeExpired 删除过期的数据，调用前需要持有分片的锁
+func (shard *rateLimitShard) removeExpired(now time.Time) {
+	for key, e := range shard.entries {
+		if now.After(e.expires) {
+			delete(shard.entries, key)
+		}
+	}
+}
+
+func (e *rateLimitEntry) tokenBucket(now time.Time, limit int, window time.Duration) RateLimitResult {
+	rate := float64(limit) / window.Seconds() // 每秒恢复的令牌数
+	e.tokens = math.Min(float64(limit), e.tokens+now.Sub(e.last).Seconds()*rate)
+	e.last = now
+
+	result := RateLimitResult{Limit: limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - e.tokens) / rate)
+	}
+	result.Remaining = int(e.tokens)
+	result.Reset = secondsToDuration((float64(limit) - e.tokens) / rate)
+	return result
+}
+
+func (e *rateLimitEntry) slidingWindow(now time.Time, limit int, window time.Duration) RateLimitResult {
+	// 移动窗口
+	if elapsed := now.Sub(e.start); elapsed >= window {
+		if elapsed >= 2*window {
+			e.prev = 0
+		} else {
+			e.prev = e.curr
+		}
+		e.curr = 0
+		e.start = e.start.Add(elapsed / window * window)
+	}
+
+	elapsed := now.Sub(e.start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimated := float64(e.prev)*weight + float64(e.curr)
+
+	result := RateLimitResult{Limit: limit, Reset: window - elapsed}
+	if estimated+1 <= float64(limit) {
+		e.curr++
+		estimated++
+		result.Allowed = true
+	} else if e.prev > 0 && e.curr < limit {
+		// 等待前一个窗口的权重下降到允许再发送一个请求
+		need := 1 - (float64(limit-e.curr-1) / float64(e.prev))
+		result.RetryAfter = time.Duration(need*float64(window)) - elapsed
+	} else {
+		result.RetryAfter = window - elapsed
+	}
+	if result.RetryAfter < 0 {
+		result.RetryAfter = 0
+	}
+	result.Remaining = limit - int(math.Ceil(estimated))
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	defer store.Close()
+
+	app := potgo.New()
+	app.Error(func(c *potgo.Context, message string, code int) {
+		c.Status(code)
+		_ = c.Text("error: " + message)
+	})
+	app.Use(RateLimit(RateLimitConfig{Limit: 2, Window: time.Minute, Store: store}))
+	app.GET("/test", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, "ok", res.Body.String())
+		assert.Equal(t, "2", res.Header().Get("RateLimit-Limit"))
+	}
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "error: Too Many Requests", res.Body.String())
+	assert.Equal(t, "0", res.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", res.Header().Get("Retry-After"))
+
+	// 其它客户端不受影响
+	req, _ = http.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.2:1234"
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "ok", res.Body.String())
+}
+
+func TestRateLimit_KeyFunc(t *testing.T) {
+	app := potgo.New()
+	app.Use(RateLimit(RateLimitConfig{Limit: 1, KeyFunc: KeyByHeader("X-API-Key")}))
+	app.GET("/test", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	// 没有 API 密钥时不限流
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+	}
+
+	codes := []int{http.StatusOK, http.StatusTooManyRequests}
+	for _, code := range codes {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-API-Key", "abc")
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, code, res.Code)
+	}
+}
+
+func TestRateLimit_AuthUser(t *testing.T) {
+	app := potgo.New()
+	app.Use(BasicAuth(map[string]string{"foo": "bar", "baz": "qux"}), RateLimit(RateLimitConfig{
+		Limit:   1,
+		KeyFunc: KeyByContext(AuthUserKey),
+	}))
+	app.GET("/test", func(c *potgo.Context) error {
+		return nil
+	})
+
+	codes := []int{http.StatusOK, http.StatusTooManyRequests}
+	for _, code := range codes {
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.SetBasicAuth("foo", "bar")
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, code, res.Code)
+	}
+
+	// 按用户分别限流
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.SetBasicAuth("baz", "qux")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestRateLimit_DefaultStore(t *testing.T) {
+	// 默认的内存存储不启动 goroutine
+	n := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		RateLimit(RateLimitConfig{Limit: 1})
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), n)
+
+	// 在 Take 时清理所在分片的过期数据
+	store := newMemoryRateLimitStore(1, false)
+	_, _ = store.Take("foo", TokenBucket, 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	store.shards[0].nextSweep = time.Time{}
+	_, _ = store.Take("bar", TokenBucket, 1, time.Minute)
+	assert.Len(t, store.shards[0].entries, 1)
+	assert.Contains(t, store.shards[0].entries, "bar")
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	tenantKey := potgo.NewKey("tenant", "")
+	app := potgo.New()
+	var keys []string
+	app.Use(func(c *potgo.Context) error {
+		c.Set("uid", 10)
+		tenantKey.Set(c, "acme")
+		return c.Next()
+	})
+	app.GET("/users/{id}", func(c *potgo.Context) error {
+		keys = append(keys, KeyByRoute()(c), KeyByContext("uid")(c), KeyByContext("undefined")(c),
+			KeyByKey(tenantKey)(c), KeyJoin(KeyByRoute(), KeyByClientIP())(c))
+		return nil
+	}).Name("user")
+
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	app.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, []string{"user", "10", "", "acme", "user|192.168.1.1"}, keys)
+}
+
+func TestRateLimitEntry_TokenBucket(t *testing.T) {
+	now := time.Now()
+	e := &rateLimitEntry{tokens: 2, last: now}
+
+	assert.True(t, e.tokenBucket(now, 2, 2*time.Second).Allowed)
+	assert.True(t, e.tokenBucket(now, 2, 2*time.Second).Allowed)
+	result := e.tokenBucket(now, 2, 2*time.Second)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// 1 秒后恢复一个令牌
+	result = e.tokenBucket(now.Add(time.Second), 2, 2*time.Second)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestRateLimitEntry_SlidingWindow(t *testing.T) {
+	now := time.Now()
+	e := &rateLimitEntry{start: now}
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, e.slidingWindow(now, 4, time.Minute).Allowed)
+	}
+	result := e.slidingWindow(now, 4, time.Minute)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.RetryAfter)
+
+	// 下一个窗口过了一半，前一个窗口的权重为 0.5，估算为 2 个请求
+	next := now.Add(90 * time.Second)
+	result = e.slidingWindow(next, 4, time.Minute)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+	assert.True(t, e.slidingWindow(next, 4, time.Minute).Allowed)
+	result = e.slidingWindow(next, 4, time.Minute)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 15*time.Second, result.RetryAfter)
+}
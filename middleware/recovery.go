@@ -27,7 +27,11 @@ func RecoveryWithWriter(out io.Writer) potgo.HandlerFunc {
 	return func(c *potgo.Context) error {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Printf("[Recovery] panic recovered:\n%s\n%s\n", err, getCallStack(3))
+				stack := getCallStack(3)
+				if pe, ok := err.(*PanicError); ok { // 在 goroutine 中发生的 panic 使用原始的调用栈
+					stack = string(pe.Stack)
+				}
+				logger.Printf("[Recovery] panic recovered:\n%s\n%s\n", err, stack)
 
 				c.Status(http.StatusInternalServerError)
 				c.Text(fmt.Sprintf("%v", err))
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/icodechef/potgo"
+)
+
+// RequestIDConfig RequestID 中间件配置
+type RequestIDConfig struct {
+	// Header 请求 ID 使用的请求头和响应头，默认为 X-Request-ID
+	Header string
+	// Generator 生成请求 ID 的函数，默认生成 32 位十六进制字符串
+	Generator func() string
+	// IgnoreIncoming 不使用请求头中的请求 ID
+	IgnoreIncoming bool
+	// DisableTrace 不处理 W3C traceparent 和 tracestate 请求头
+	DisableTrace bool
+}
+
+// RequestID 返回请求 ID 中间件
+//
+// 优先使用请求头中的请求 ID，没有时生成新的请求 ID，并在响应头中返回。
+// 同时解析 W3C traceparent 和 tracestate 请求头，为当前请求创建追踪上下文。
+// 请求 ID 和追踪上下文可以通过 c.RequestID() 和 c.TraceContext() 获取
+func RequestID(config ...RequestIDConfig) potgo.HandlerFunc {
+	var cfg RequestIDConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.Header == "" {
+		cfg.Header = "X-Request-ID"
+	}
+	if cfg.Generator == nil {
+		cfg.Generator = generateRequestID
+	}
+
+	return func(c *potgo.Context) error {
+		var id string
+		if !cfg.IgnoreIncoming {
+			id = c.Request.Header.Get(cfg.Header)
+			if !validRequestID(id) {
+				id = ""
+			}
+		}
+		if id == "" {
+			id = cfg.Generator()
+		}
+
+		potgo.RequestIDKey.Set(c, id)
+		c.Response.Header().Set(cfg.Header, id)
+
+		if !cfg.DisableTrace {
+			potgo.TraceContextKey.Set(c, potgo.NewTraceContext(
+				c.Request.Header.Get("traceparent"),
+				c.Request.Header.Get("tracestate"),
+			))
+		}
+
+		return c.Next()
+	}
+}
+
+func generateRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID 只接受长度不超过 128 的可打印 ASCII 字符，防止日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	app := potgo.New()
+	app.Use(RequestID())
+	app.GET("/", func(c *potgo.Context) error {
+		return c.Text(c.RequestID())
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	id := res.Header().Get("X-Request-ID")
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, res.Body.String())
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "upstream-id")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "upstream-id", res.Header().Get("X-Request-ID"))
+	assert.Equal(t, "upstream-id", res.Body.String())
+
+	// 包含控制字符的请求 ID 被忽略
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "bad\nid")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Len(t, res.Header().Get("X-Request-ID"), 32)
+}
+
+func TestRequestID_Config(t *testing.T) {
+	app := potgo.New()
+	app.Use(RequestID(RequestIDConfig{
+		Header:         "X-Trace",
+		Generator:      func() string { return "generated" },
+		IgnoreIncoming: true,
+		DisableTrace:   true,
+	}))
+	app.GET("/", func(c *potgo.Context) error {
+		assert.Nil(t, c.TraceContext())
+		return c.Text(c.RequestID())
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Trace", "upstream-id")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "generated", res.Header().Get("X-Trace"))
+	assert.Equal(t, "generated", res.Body.String())
+}
+
+func TestRequestID_TraceContext(t *testing.T) {
+	var tc *potgo.TraceContext
+
+	app := potgo.New()
+	app.Use(RequestID())
+	app.GET("/", func(c *potgo.Context) error {
+		tc = c.TraceContext()
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tc.ParentID)
+	assert.Len(t, tc.SpanID, 16)
+	assert.NotEqual(t, tc.ParentID, tc.SpanID)
+	assert.True(t, tc.Sampled())
+
+	out := http.Header{}
+	tc.Inject(out)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+tc.SpanID+"-01", out.Get("traceparent"))
+	assert.Equal(t, "congo=t61rcWkgMzE", out.Get("tracestate"))
+
+	// 无效的 traceparent 开始新的追踪
+	req, _ = http.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "congo=t61rcWkgMzE")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Len(t, tc.TraceID, 32)
+	assert.NotEqual(t, "00000000000000000000000000000000", tc.TraceID)
+	assert.Equal(t, "", tc.ParentID)
+	assert.Equal(t, "", tc.State)
+}
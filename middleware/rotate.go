@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotateConfig 日志文件轮转配置
+type RotateConfig struct {
+	// MaxSize 文件超过 MaxSize 字节时轮转，0 表示不按大小轮转
+	MaxSize int64
+	// Interval 按时间间隔轮转，例如 24 * time.Hour，0 表示不按时间轮转
+	Interval time.Duration
+	// MaxBackups 保留的旧文件数量，0 表示全部保留
+	MaxBackups int
+}
+
+// backupTimeFormat 旧文件名中的时间格式
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateFile 以追加方式写入并按大小或时间轮转的文件，可以安全地并发写入
+//
+// 轮转时当前文件被重命名为 name-20060102T150405.000.ext，然后创建新的文件。
+// 清理旧文件时只删除符合这个命名格式的文件
+type RotateFile struct {
+	mu       sync.Mutex
+	filename string
+	config   RotateConfig
+	file     *os.File
+	size     int64
+	period   time.Time
+	now      func() time.Time
+}
+
+// NewRotateFile 以追加方式打开文件，文件不存在时创建
+func NewRotateFile(filename string, config ...RotateConfig) (*RotateFile, error) {
+	f := &RotateFile{filename: filename, now: time.Now}
+	if len(config) > 0 {
+		f.config = config[0]
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotateFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.period = f.periodOf(f.now())
+	return nil
+}
+
+// periodOf 返回时间所在的轮转周期的开始时间
+func (f *RotateFile) periodOf(t time.Time) time.Time {
+	if f.config.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.Truncate(f.config.Interval)
+}
+
+// Write 写入数据，实现 io.Writer 接口
+func (f *RotateFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if (f.config.MaxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.config.MaxSize) ||
+		(f.config.Interval > 0 && !f.periodOf(f.now()).Equal(f.period)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate 立即轮转文件
+func (f *RotateFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+func (f *RotateFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+
+	ext := filepath.Ext(f.filename)
+	backup := strings.TrimSuffix(f.filename, ext) + "-" + f.now().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.filename, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if f.config.MaxBackups > 0 {
+		if backups := f.backups(); len(backups) > f.config.MaxBackups {
+			for _, b := range backups[:len(backups)-f.config.MaxBackups] {
+				_ = os.Remove(b)
+			}
+		}
+	}
+
+	return f.open()
+}
+
+// backups 返回按时间排序的旧文件，例如 access.log 的旧文件为 access-20060102T150405.000.log，
+// 不包括 access-error.log 等其它文件
+func (f *RotateFile) backups() []string {
+	dir := filepath.Dir(f.filename)
+	ext := filepath.Ext(f.filename)
+	prefix := strings.TrimSuffix(filepath.Base(f.filename), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) ||
+			len(name) != len(prefix)+len(backupTimeFormat)+len(ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, name[len(prefix):len(name)-len(ext)]); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Strings(backups)
+	return backups
+}
+
+// Close 关闭文件
+func (f *RotateFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateFile_Size(t *testing.T) {
+	dir, err := ioutil.TempDir("", "potgo-rotate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// 不是旧文件，清理时不删除
+	other := filepath.Join(dir, "access-error.log")
+	assert.Nil(t, ioutil.WriteFile(other, []byte("error\n"), 0644))
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := filepath.Join(dir, "access.log")
+	f, err := NewRotateFile(file, RotateConfig{MaxSize: 10, MaxBackups: 2})
+	assert.Nil(t, err)
+	defer f.Close()
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err = f.Write([]byte("12345678\n"))
+		assert.Nil(t, err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "access-2020*.log"))
+	assert.Len(t, backups, 2)
+	assert.FileExists(t, other)
+
+	b, _ := ioutil.ReadFile(file)
+	assert.Equal(t, "12345678\n", string(b))
+}
+
+func TestRotateFile_Interval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "potgo-rotate")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 1, 23, 0, 0, 0, time.UTC)
+	file := filepath.Join(dir, "access.log")
+	f, err := NewRotateFile(file, RotateConfig{Interval: 24 * time.Hour})
+	assert.Nil(t, err)
+	defer f.Close()
+	f.now = func() time.Time {
+		return now
+	}
+
+	_, _ = f.Write([]byte("day1\n"))
+	now = now.Add(2 * time.Hour)
+	_, _ = f.Write([]byte("day2\n"))
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "access-20200102T010000.000.log"))
+	assert.Equal(t, "day1\n", string(b))
+	b, _ = ioutil.ReadFile(file)
+	assert.Equal(t, "day2\n", string(b))
+
+	assert.Nil(t, f.Rotate())
+	b, _ = ioutil.ReadFile(file)
+	assert.Equal(t, "", string(b))
+}
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/icodechef/potgo"
+)
+
+// CSPNoncePlaceholder ContentSecurityPolicy 中的 nonce 占位符，每个请求会替换为新生成的 nonce
+const CSPNoncePlaceholder = "{nonce}"
+
+// SecureConfig 安全响应头中间件配置
+//
+// 字符串类型的响应头为空时使用默认值，设置为 "-" 时不发送该响应头
+type SecureConfig struct {
+	// HSTSMaxAge Strict-Transport-Security 的 max-age，单位为秒，默认为一年，小于 0 时不发送。只在 HTTPS 请求中发送
+	HSTSMaxAge int
+	// HSTSIncludeSubdomains 添加 includeSubDomains
+	HSTSIncludeSubdomains bool
+	// HSTSPreload 添加 preload
+	HSTSPreload bool
+	// ContentSecurityPolicy 内容安全策略，默认不发送。包含 {nonce} 时为每个请求生成 nonce，
+	// 例如 "script-src 'self' 'nonce-{nonce}'"
+	ContentSecurityPolicy string
+	// CSPReportOnly 使用 Content-Security-Policy-Report-Only 响应头
+	CSPReportOnly bool
+	// FrameOptions X-Frame-Options，默认为 SAMEORIGIN
+	FrameOptions string
+	// ContentTypeOptions X-Content-Type-Options，默认为 nosniff
+	ContentTypeOptions string
+	// ReferrerPolicy Referrer-Policy，默认为 strict-origin-when-cross-origin
+	ReferrerPolicy string
+	// PermissionsPolicy Permissions-Policy，默认为 camera=(), microphone=(), geolocation=()
+	PermissionsPolicy string
+	// SSLRedirect 将 HTTP 请求重定向到 HTTPS，协议由 c.Scheme() 判断
+	SSLRedirect bool
+	// SSLHost 重定向到 HTTPS 时使用的主机，默认为当前请求的主机
+	SSLHost string
+	// CanonicalHost 不为空时，将其它主机的请求重定向到该主机
+	CanonicalHost string
+	// AllowedHosts 允许的主机，不为空时其它主机的请求返回 400 错误
+	AllowedHosts []string
+}
+
+// Secure 返回添加安全响应头的中间件
+//
+//	app.Use(middleware.Secure(middleware.SecureConfig{
+//		ContentSecurityPolicy: "default-src 'self'; script-src 'self' 'nonce-{nonce}'",
+//		SSLRedirect:           true,
+//	}))
+func Secure(config ...SecureConfig) potgo.HandlerFunc {
+	var cfg SecureConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.HSTSMaxAge == 0 {
+		cfg.HSTSMaxAge = 31536000
+	}
+
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	headers := [...][2]string{
+		{"X-Frame-Options", secureHeaderValue(cfg.FrameOptions, "SAMEORIGIN")},
+		{"X-Content-Type-Options", secureHeaderValue(cfg.ContentTypeOptions, "nosniff")},
+		{"Referrer-Policy", secureHeaderValue(cfg.ReferrerPolicy, "strict-origin-when-cross-origin")},
+		{"Permissions-Policy", secureHeaderValue(cfg.PermissionsPolicy, "camera=(), microphone=(), geolocation=()")},
+	}
+
+	cspHeader := "Content-Security-Policy"
+	if cfg.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	csp := secureHeaderValue(cfg.ContentSecurityPolicy, "")
+	useNonce := strings.Contains(csp, CSPNoncePlaceholder)
+
+	return func(c *potgo.Context) error {
+		if len(cfg.AllowedHosts) > 0 && !hostAllowed(c.Host(), cfg.AllowedHosts) {
+			return potgo.NewHTTPError(http.StatusBadRequest)
+		}
+
+		https := c.Scheme() == "https"
+		host := c.Host()
+
+		if cfg.CanonicalHost != "" && !strings.EqualFold(host, cfg.CanonicalHost) {
+			scheme := "http"
+			if https || cfg.SSLRedirect {
+				scheme = "https"
+			}
+			return secureRedirect(c, scheme+"://"+cfg.CanonicalHost)
+		}
+		if cfg.SSLRedirect && !https {
+			if cfg.SSLHost != "" {
+				host = cfg.SSLHost
+			}
+			return secureRedirect(c, "https://"+host)
+		}
+
+		header := c.Response.Header()
+		for _, h := range headers {
+			if h[1] != "" {
+				header.Set(h[0], h[1])
+			}
+		}
+		if https && hsts != "" {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+
+		if csp != "" {
+			policy := csp
+			if useNonce {
+				nonce, err := generateNonce()
+				if err != nil {
+					return err
+				}
+				potgo.CSPNonceKey.Set(c, nonce)
+				policy = strings.Replace(csp, CSPNoncePlaceholder, nonce, -1)
+			}
+			header.Set(cspHeader, policy)
+		}
+
+		return c.Next()
+	}
+}
+
+// secureHeaderValue 返回响应头的值，"-" 表示不发送
+func secureHeaderValue(value, defaultValue string) string {
+	if value == "-" {
+		return ""
+	}
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// secureRedirect 重定向到 base 加上当前请求的路径和查询字符串，GET 和 HEAD 请求使用 301，其它请求使用 308 以保留请求方法
+func secureRedirect(c *potgo.Context, base string) error {
+	code := http.StatusMovedPermanently
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		code = http.StatusPermanentRedirect
+	}
+	c.Abort()
+	return c.Redirect(base+c.Request.URL.RequestURI(), code)
+}
+
+// hostAllowed 主机是否在允许的列表中，列表中的主机可以不包含端口
+func hostAllowed(host string, allowed []string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, host) || strings.EqualFold(a, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecure_Headers(t *testing.T) {
+	app := potgo.New()
+	app.Use(Secure())
+	app.GET("/", func(c *potgo.Context) error {
+		return c.Text(c.CSPNonce())
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	assert.Equal(t, "SAMEORIGIN", res.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", res.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", res.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "camera=(), microphone=(), geolocation=()", res.Header().Get("Permissions-Policy"))
+	assert.Equal(t, "", res.Header().Get("Content-Security-Policy"))
+	// HSTS 只在 HTTPS 请求中发送
+	assert.Equal(t, "", res.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "", res.Body.String())
+
+	req.TLS = &tls.ConnectionState{}
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "max-age=31536000", res.Header().Get("Strict-Transport-Security"))
+}
+
+func TestSecure_Config(t *testing.T) {
+	app := potgo.New()
+	app.Use(Secure(SecureConfig{
+		HSTSMaxAge:            600,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		FrameOptions:          "DENY",
+		PermissionsPolicy:     "-",
+		ContentSecurityPolicy: "default-src 'self'",
+		CSPReportOnly:         true,
+	}))
+	app.GET("/", func(c *potgo.Context) error {
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.TLS = &tls.ConnectionState{}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	assert.Equal(t, "max-age=600; includeSubDomains; preload", res.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "DENY", res.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "", res.Header().Get("Permissions-Policy"))
+	assert.Equal(t, "default-src 'self'", res.Header().Get("Content-Security-Policy-Report-Only"))
+	assert.Equal(t, "", res.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecure_CSPNonce(t *testing.T) {
+	app := potgo.New()
+	_ = app.RegisterView(potgo.HTML("../testdata/views_3", ".html"))
+	app.Use(Secure(SecureConfig{
+		ContentSecurityPolicy: "script-src 'self' 'nonce-{nonce}'",
+	}))
+	app.GET("/", func(c *potgo.Context) error {
+		return c.View("csp.html")
+	})
+
+	nonces := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "/", nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+
+		csp := res.Header().Get("Content-Security-Policy")
+		assert.True(t, strings.HasPrefix(csp, "script-src 'self' 'nonce-"))
+		nonce := strings.TrimSuffix(strings.TrimPrefix(csp, "script-src 'self' 'nonce-"), "'")
+		assert.Len(t, nonce, 22)
+		assert.Equal(t, `<script nonce="`+nonce+`"></script>`, res.Body.String())
+		nonces[nonce] = true
+	}
+	assert.Len(t, nonces, 2)
+}
+
+func TestSecure_Redirect(t *testing.T) {
+	app := potgo.New()
+	_ = app.SetTrustedProxies("10.0.0.1")
+	app.Use(Secure(SecureConfig{SSLRedirect: true}))
+	app.Any("/users", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com/users?page=2", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusMovedPermanently, res.Code)
+	assert.Equal(t, "https://example.com/users?page=2", res.Header().Get("Location"))
+
+	req, _ = http.NewRequest("POST", "http://example.com/users", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusPermanentRedirect, res.Code)
+
+	// 受信任的代理已经使用 HTTPS
+	req, _ = http.NewRequest("GET", "http://example.com/users", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "ok", res.Body.String())
+}
+
+func TestSecure_Hosts(t *testing.T) {
+	app := potgo.New()
+	app.Use(Secure(SecureConfig{
+		CanonicalHost: "www.example.com",
+		AllowedHosts:  []string{"example.com", "www.example.com"},
+	}))
+	app.GET("/", func(c *potgo.Context) error {
+		return c.Text("ok")
+	})
+
+	req, _ := http.NewRequest("GET", "http://example.com:8080/?a=1", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusMovedPermanently, res.Code)
+	assert.Equal(t, "http://www.example.com/?a=1", res.Header().Get("Location"))
+
+	req, _ = http.NewRequest("GET", "http://evil.com/", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	req, _ = http.NewRequest("GET", "http://www.example.com/", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/icodechef/potgo"
+)
+
+// TimeoutConfig 超时中间件配置
+type TimeoutConfig struct {
+	// StatusCode 超时时的状态码，默认为 503，也可以使用 504
+	StatusCode int
+	// Routes 指定路由的超时时间，键为路由名称或者路由的路径模板，小于等于 0 表示不限制
+	Routes map[string]time.Duration
+}
+
+// Timeout 返回超时中间件
+//
+// 剩余的 HandlerFunc 在 goroutine 中执行，请求的 context 在超时后取消，HandlerFunc 应该使用
+// c.Request.Context() 及时停止。响应先写入缓冲区，超时后写入的数据会被丢弃，
+// 超时时返回 503 错误，交给应用的错误处理程序处理。
+//
+// 没有超时时，HandlerFunc 在上下文中保存的数据、视图数据和闪存数据会合并到原上下文；
+// 超时后原上下文在 goroutine 结束后才会放回对象池。goroutine 中的 panic 使用 *PanicError 重新抛出，
+// 其中包含 goroutine 的调用栈
+func Timeout(d time.Duration, config ...TimeoutConfig) potgo.HandlerFunc {
+	var cfg TimeoutConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.StatusCode == 0 {
+		cfg.StatusCode = http.StatusServiceUnavailable
+	}
+
+	return func(c *potgo.Context) error {
+		timeout := d
+		if r := c.Route(); r != nil && cfg.Routes != nil {
+			if v, ok := cfg.Routes[r.GetName()]; ok && r.GetName() != "" {
+				timeout = v
+			} else if v, ok := cfg.Routes[r.Path()]; ok {
+				timeout = v
+			}
+		}
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		// 从原响应头开始，HandlerFunc 可以读取或删除外层中间件设置的响应头
+		tw := &timeoutWriter{header: c.Response.Header().Clone()}
+		f := c.Fork(tw)
+		f.Request = c.Request.WithContext(ctx)
+
+		done := make(chan error, 1)
+		panicked := make(chan *PanicError, 1)
+		go func() {
+			var err error
+			defer func() {
+				p := recover()
+
+				tw.mu.Lock()
+				tw.finished = true
+				abandoned := tw.timedOut
+				tw.mu.Unlock()
+				if abandoned {
+					// 已经超时，原上下文在 Fork 的上下文结束后才会放回对象池
+					f.Release()
+					return
+				}
+				if p != nil {
+					panicked <- &PanicError{Value: p, Stack: debug.Stack()}
+					return
+				}
+				done <- err
+			}()
+			err = f.Next()
+		}()
+
+		select {
+		case p := <-panicked:
+			f.Release()
+			panic(p)
+		case err := <-done:
+			// 合并 HandlerFunc 在上下文中保存的数据、视图数据和闪存数据
+			c.Merge(f)
+			f.Release()
+
+			// 整体替换响应头，避免 HandlerFunc 设置的同名响应头覆盖外层中间件设置的值
+			header := c.Response.Header()
+			for k := range header {
+				delete(header, k)
+			}
+			for k, vv := range tw.header {
+				header[k] = vv
+			}
+			if tw.code > 0 {
+				c.Status(tw.code)
+				if tw.buf.Len() > 0 {
+					_, _ = c.Write(tw.buf.Bytes())
+				} else {
+					c.Response.WriteHeaderNow()
+				}
+			}
+			c.Abort()
+			return err
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			finished := tw.finished
+			tw.mu.Unlock()
+			if finished {
+				f.Release()
+			}
+
+			c.Abort()
+			if ctx.Err() == context.DeadlineExceeded {
+				return potgo.NewHTTPError(cfg.StatusCode)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// PanicError Timeout 执行的 HandlerFunc 中发生的 panic，Stack 为发生 panic 时 goroutine 的调用栈
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// Error 返回 panic 的值，不包含调用栈
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Unwrap panic 的值为 error 时返回该 error
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// timeoutWriter 将响应写入缓冲区，超时后拒绝写入
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+	finished bool // HandlerFunc 是否已经返回
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.code > 0 {
+		return
+	}
+	w.code = code
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/icodechef/potgo"
+	"github.com/icodechef/potgo/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+func slowHandler(d time.Duration) potgo.HandlerFunc {
+	return func(c *potgo.Context) error {
+		select {
+		case <-time.After(d):
+			c.Header("X-Slow", "1")
+			return c.Text("done")
+		case <-c.Request.Context().Done():
+			return c.Request.Context().Err()
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	app := potgo.New()
+	app.Error(func(c *potgo.Context, message string, code int) {
+		c.Status(code)
+		_ = c.Text("error: " + message)
+	})
+	app.Use(Timeout(20*time.Millisecond, TimeoutConfig{
+		StatusCode: http.StatusGatewayTimeout,
+		Routes:     map[string]time.Duration{"upload": time.Second},
+	}))
+	app.GET("/fast", slowHandler(0))
+	app.GET("/slow", slowHandler(time.Second))
+	app.GET("/upload", slowHandler(50*time.Millisecond)).Name("upload")
+	app.GET("/created", func(c *potgo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	})
+
+	req, _ := http.NewRequest("GET", "/fast", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "done", res.Body.String())
+	assert.Equal(t, "1", res.Header().Get("X-Slow"))
+
+	req, _ = http.NewRequest("GET", "/slow", nil)
+	res = httptest.NewRecorder()
+	start := time.Now()
+	app.ServeHTTP(res, req)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Equal(t, http.StatusGatewayTimeout, res.Code)
+	assert.Equal(t, "error: Gateway Timeout", res.Body.String())
+	assert.Equal(t, "", res.Header().Get("X-Slow"))
+
+	req, _ = http.NewRequest("GET", "/upload", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "done", res.Body.String())
+
+	req, _ = http.NewRequest("GET", "/created", nil)
+	res = httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusCreated, res.Code)
+}
+
+func TestTimeout_LateWrite(t *testing.T) {
+	written := make(chan error, 1)
+
+	app := potgo.New()
+	app.Use(Timeout(10 * time.Millisecond))
+	app.GET("/test", func(c *potgo.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		_, err := c.Write([]byte("late"))
+		written <- err
+		return err
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	assert.Equal(t, http.ErrHandlerTimeout, <-written)
+	assert.NotContains(t, res.Body.String(), "late")
+}
+
+func TestTimeout_Panic(t *testing.T) {
+	buf := new(bytes.Buffer)
+	app := potgo.New()
+	app.Use(RecoveryWithWriter(buf), Timeout(time.Second))
+	app.GET("/test", func(c *potgo.Context) error {
+		panic("abc")
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, "abc", res.Body.String())
+	// 日志中包含 goroutine 中发生 panic 的位置
+	assert.Contains(t, buf.String(), "TestTimeout_Panic.func1")
+}
+
+func TestTimeout_Merge(t *testing.T) {
+	app := potgo.New()
+	app.SetSecretKeys([]byte("secret"))
+	app.Use(func(c *potgo.Context) error {
+		err := c.Next()
+		user, _ := c.GetString("user")
+		c.Header("X-User", user)
+		return err
+	}, Timeout(time.Second))
+	app.GET("/test", func(c *potgo.Context) error {
+		c.Set("user", "foo")
+		c.ViewData("title", "bar")
+		c.Flash("success", "saved")
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "foo", res.Header().Get("X-User"))
+	assert.Contains(t, res.Header().Get("Set-Cookie"), "potgo_flash=")
+}
+
+func TestTimeout_ReleaseAfterGoroutine(t *testing.T) {
+	finish := make(chan struct{})
+	var parent *potgo.Context
+
+	app := potgo.New()
+	app.Debug(true)
+	app.Use(func(c *potgo.Context) error {
+		parent = c
+		return c.Next()
+	}, Timeout(10*time.Millisecond))
+	app.GET("/test", func(c *potgo.Context) error {
+		<-finish
+		c.Set("foo", "bar")
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	// goroutine 还在执行，原上下文没有被释放
+	assert.NotPanics(t, func() {
+		parent.Param("id")
+	})
+
+	close(finish)
+	assert.Eventually(t, func() (released bool) {
+		defer func() { released = recover() != nil }()
+		parent.Param("id")
+		return
+	}, time.Second, time.Millisecond)
+}
+
+func TestTimeout_Session(t *testing.T) {
+	finish := make(chan struct{})
+	written := make(chan struct{})
+
+	app := potgo.New()
+	app.Use(sessions.Middleware(sessions.NewMemoryStore()), Timeout(10*time.Millisecond))
+	app.GET("/test", func(c *potgo.Context) error {
+		defer close(written)
+		// 超时后仍然修改会话，与会话中间件保存会话同时进行
+		for i := 0; ; i++ {
+			select {
+			case <-finish:
+				// 会话中间件返回后对会话的修改被忽略
+				c.Session().Set("foo", "bar")
+				assert.Nil(t, c.Session().Get("foo"))
+				return nil
+			default:
+				c.Session().Set("count", i)
+			}
+		}
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	close(finish)
+	<-written
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.Contains(t, res.Header().Get("Set-Cookie"), sessions.DefaultOptions.Name+"=")
+}
+
+func TestTimeout_Header(t *testing.T) {
+	app := potgo.New()
+	app.Use(Compress(gzip.DefaultCompression), func(c *potgo.Context) error {
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("X-Outer", "1")
+		return c.Next()
+	}, Timeout(time.Second))
+	app.GET("/test", func(c *potgo.Context) error {
+		c.Response.Header().Add("Vary", "Origin")
+		c.Response.Header().Del("X-Frame-Options")
+		return c.Text(c.Response.Header().Get("X-Outer") + largeBody)
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	assert.Equal(t, "gzip", res.Header().Get("Content-Encoding"))
+	assert.Equal(t, []string{"Accept-Encoding", "Origin"}, res.Header().Values("Vary"))
+	assert.Equal(t, "", res.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "1", res.Header().Get("X-Outer"))
+}
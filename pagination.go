@@ -0,0 +1,122 @@
+package potgo
+
+import (
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageParam 分页链接中页码使用的查询参数
+const PageParam = "page"
+
+// paginationWindow 当前页前后显示的页数
+const paginationWindow = 2
+
+// Pagination 分页链接
+//
+//	{{ with pagination .page .totalPages }}{{ .HTML }}{{ end }}
+type Pagination struct {
+	Current int
+	Total   int
+	Prev    *PageLink  // 上一页，当前为第一页时为 nil
+	Next    *PageLink  // 下一页，当前为最后一页时为 nil
+	Pages   []PageLink // 第一页、最后一页和当前页前后的页，省略的页使用 Gap 表示
+}
+
+// PageLink 一页的链接
+type PageLink struct {
+	Number  int
+	URL     string
+	Current bool
+	Gap     bool // 省略的页
+}
+
+// NewPagination 创建分页链接，链接使用 u 的路径和查询参数，并把 page 参数替换为页码
+func NewPagination(current, total int, u *url.URL) *Pagination {
+	if total < 1 {
+		total = 1
+	}
+	if current < 1 {
+		current = 1
+	} else if current > total {
+		current = total
+	}
+
+	p := &Pagination{Current: current, Total: total}
+	link := func(n int) PageLink {
+		return PageLink{Number: n, URL: pageURL(u, n), Current: n == current}
+	}
+	if current > 1 {
+		prev := link(current - 1)
+		p.Prev = &prev
+	}
+	if current < total {
+		next := link(current + 1)
+		p.Next = &next
+	}
+
+	// 第一页、当前页前后 paginationWindow 页和最后一页，与总页数无关
+	start, end := current-paginationWindow, current+paginationWindow
+	if start < 2 {
+		start = 2
+	}
+	if end > total-1 {
+		end = total - 1
+	}
+	p.Pages = append(p.Pages, link(1))
+	if start > 2 {
+		p.Pages = append(p.Pages, PageLink{Gap: true})
+	}
+	for n := start; n <= end; n++ {
+		p.Pages = append(p.Pages, link(n))
+	}
+	if end < total-1 {
+		p.Pages = append(p.Pages, PageLink{Gap: true})
+	}
+	if total > 1 {
+		p.Pages = append(p.Pages, link(total))
+	}
+	return p
+}
+
+// HTML 返回默认的分页链接，只有一页时返回空字符串
+func (p *Pagination) HTML() template.HTML {
+	if p.Total <= 1 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(`<nav class="pagination">`)
+	if p.Prev != nil {
+		b.WriteString(`<a href="` + template.HTMLEscapeString(p.Prev.URL) + `" rel="prev">&laquo;</a>`)
+	}
+	for _, page := range p.Pages {
+		switch {
+		case page.Gap:
+			b.WriteString(`<span class="gap">&hellip;</span>`)
+		case page.Current:
+			b.WriteString(`<span class="current">` + strconv.Itoa(page.Number) + `</span>`)
+		default:
+			b.WriteString(`<a href="` + template.HTMLEscapeString(page.URL) + `">` + strconv.Itoa(page.Number) + `</a>`)
+		}
+	}
+	if p.Next != nil {
+		b.WriteString(`<a href="` + template.HTMLEscapeString(p.Next.URL) + `" rel="next">&raquo;</a>`)
+	}
+	b.WriteString(`</nav>`)
+	return template.HTML(b.String())
+}
+
+func pageURL(u *url.URL, n int) string {
+	var query url.Values
+	path := ""
+	if u != nil {
+		query = u.Query()
+		path = u.EscapedPath()
+	} else {
+		query = make(url.Values)
+	}
+	query.Set(PageParam, strconv.Itoa(n))
+	return path + "?" + query.Encode()
+}
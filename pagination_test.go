@@ -0,0 +1,64 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func TestNewPagination(t *testing.T) {
+	u, _ := url.Parse("/posts?tag=go&page=6")
+	p := NewPagination(6, 20, u)
+	assert.Equal(t, "/posts?page=5&tag=go", p.Prev.URL)
+	assert.Equal(t, 7, p.Next.Number)
+
+	var numbers []int
+	for _, page := range p.Pages {
+		if page.Gap {
+			numbers = append(numbers, 0)
+		} else {
+			numbers = append(numbers, page.Number)
+		}
+	}
+	assert.Equal(t, []int{1, 0, 4, 5, 6, 7, 8, 0, 20}, numbers)
+	assert.True(t, p.Pages[4].Current)
+
+	p = NewPagination(0, 3, nil)
+	assert.Equal(t, 1, p.Current)
+	assert.Nil(t, p.Prev)
+	assert.Equal(t, "?page=2", p.Next.URL)
+
+	p = NewPagination(9, 3, nil)
+	assert.Equal(t, 3, p.Current)
+	assert.Nil(t, p.Next)
+
+	assert.Equal(t, "", string(NewPagination(1, 1, nil).HTML()))
+
+	// 保留路径中转义的字符
+	u, _ = url.Parse("/tags/a%2Fb%3F%23?page=2")
+	assert.Equal(t, "/tags/a%2Fb%3F%23?page=1", NewPagination(2, 3, u).Prev.URL)
+}
+
+func TestNewPagination_Window(t *testing.T) {
+	numbers := func(p *Pagination) []int {
+		var numbers []int
+		for _, page := range p.Pages {
+			if page.Gap {
+				numbers = append(numbers, 0)
+			} else {
+				numbers = append(numbers, page.Number)
+			}
+		}
+		return numbers
+	}
+
+	assert.Equal(t, []int{1}, numbers(NewPagination(1, 1, nil)))
+	assert.Equal(t, []int{1, 2}, numbers(NewPagination(2, 2, nil)))
+	assert.Equal(t, []int{1, 2, 3, 0, 10}, numbers(NewPagination(1, 10, nil)))
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 0, 10}, numbers(NewPagination(4, 10, nil)))
+	assert.Equal(t, []int{1, 0, 8, 9, 10}, numbers(NewPagination(10, 10, nil)))
+
+	// 页数很多时只生成窗口内的页
+	p := NewPagination(500000000, 1000000000, nil)
+	assert.Equal(t, []int{1, 0, 499999998, 499999999, 500000000, 500000001, 500000002, 0, 1000000000}, numbers(p))
+}
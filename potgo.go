@@ -1,13 +1,18 @@
 package potgo
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"runtime"
 	"strings"
 	"sync"
@@ -45,7 +50,11 @@ type Application struct {
 	context         *Context
 	notFoundHandler HandlerFunc
 	errorHandler    ErrorHandlerFunc
-	view            ViewEngine
+	view            ViewEngine            // 第一个注册的视图引擎
+	views           map[string]ViewEngine // 按扩展名注册的视图引擎
+	debug           bool
+	secretKeys      []*CookieCodec
+	trustedProxies  []*net.IPNet
 }
 
 // New 创建一个新的 Application
@@ -68,10 +77,60 @@ func New() *Application {
 	return app
 }
 
-// RegisterView 注册视图
-func (app *Application) RegisterView(view ViewEngine) error {
-	app.view = view
-	return app.view.Load()
+// RegisterView 注册视图引擎
+//
+// 可以注册多个使用不同扩展名的视图引擎，渲染时根据视图的扩展名选择视图引擎，
+// 没有对应扩展名的视图引擎时使用第一个注册的视图引擎。
+// 没有指定 extension 时使用视图引擎 Extension 方法返回的扩展名，没有实现该方法时为 .html
+func (app *Application) RegisterView(view ViewEngine, extension ...string) error {
+	if app.view == nil {
+		app.view = view
+	}
+	if app.views == nil {
+		app.views = make(map[string]ViewEngine)
+	}
+	if len(extension) == 0 {
+		extension = []string{viewExtension(view)}
+	}
+	for _, ext := range extension {
+		app.views[ext] = view
+	}
+	return view.Load()
+}
+
+// viewEngine 返回视图扩展名对应的视图引擎
+func (app *Application) viewEngine(name string) ViewEngine {
+	if view, ok := app.views[path.Ext(name)]; ok {
+		return view
+	}
+	return app.view
+}
+
+// renderView 使用视图扩展名对应的视图引擎渲染视图
+func (app *Application) renderView(w io.Writer, name string, layout string, data interface{}, c *Context) error {
+	if app.view == nil {
+		return errors.New("view engine is missing, pls use `RegisterView`")
+	}
+	return app.viewEngine(name).Render(w, name, layout, data, c)
+}
+
+// RenderToString 在请求之外渲染视图并返回字符串，例如在后台任务中渲染 HTML 邮件
+//
+// 视图中的 route 函数可以正常使用，flashes、csrfToken 等请求相关的函数返回零值
+func (app *Application) RenderToString(name string, layout string, data interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := app.renderView(buf, name, layout, data, &Context{app: app}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Debug 设置调试模式
+//
+// 调试模式下请求结束后 Context 不再放回对象池，并被标记为已释放，
+// 之后继续使用该 Context 会 panic，便于发现在 goroutine 中直接使用 Context 的问题
+func (app *Application) Debug(enabled bool) {
+	app.debug = enabled
 }
 
 // Run http.ListenAndServe(addr, app) 的快捷方式
@@ -114,6 +173,22 @@ func (app *Application) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		if value.handlers != nil {
 			c.pKeys = value.pKeys
 			c.handlers = value.handlers
+			c.route = value.route
+		}
+	}
+
+	if c.handlers == nil && req.Method == http.MethodOptions {
+		// 没有注册 OPTIONS 路由时自动响应，并执行匹配的路由的中间件，包括路由分组的中间件，以便处理 CORS 预检请求
+		if allow, method := app.allowedMethods(req.URL.Path, c.pValues, req.Header.Get("Access-Control-Request-Method")); allow != "" {
+			// 重新匹配选中的路由，保存该路由的参数，中间件可以使用 Param 和 Route
+			value := app.trees[method].getRoute(req.URL.Path, c.pValues)
+			c.pKeys = value.pKeys
+			c.route = value.route
+			// 最后一个是路由的处理程序
+			middleware := value.handlers[:len(value.handlers)-1]
+			c.handlers = make([]HandlerFunc, 0, len(middleware)+1)
+			c.handlers = append(c.handlers, middleware...)
+			c.handlers = append(c.handlers, optionsHandler(allow))
 		}
 	}
 
@@ -124,8 +199,12 @@ func (app *Application) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if err := c.Next(); err != nil {
 		app.handleError(c, err)
 	}
+	// 没有写入任何数据时，执行 Response.Before 注册的函数
+	c.Response.runBefore()
+	c.Response.runAfter()
 
-	app.pool.Put(c)
+	// 还有 Fork 返回的上下文没有结束时，由最后一个调用 Release 的上下文放回对象池
+	c.done()
 }
 
 // addRoute 添加路由
@@ -150,22 +229,58 @@ func (app *Application) addRoute(method, path string, handlers []HandlerFunc) *R
 	return r
 }
 
+// allowedMethods 返回路径匹配的路由的所有 HTTP 方法，以逗号分隔，
+// 以及执行中间件的路由的 HTTP 方法，优先使用 preferred 方法，例如预检请求的 Access-Control-Request-Method，
+// 否则使用第一个匹配的方法。pValues 只作为匹配时的缓冲区
+func (app *Application) allowedMethods(path string, pValues []string, preferred string) (string, string) {
+	var allow []string
+	var matched string
+	for _, method := range methods {
+		if method == http.MethodOptions {
+			continue
+		}
+		if root := app.trees[method]; root != nil {
+			if value := root.getRoute(path, pValues); value.handlers != nil {
+				allow = append(allow, method)
+				if matched == "" || method == preferred {
+					matched = method
+				}
+			}
+		}
+	}
+	if len(allow) == 0 {
+		return "", ""
+	}
+	return strings.Join(append(allow, http.MethodOptions), ", "), matched
+}
+
+// optionsHandler 自动响应 OPTIONS 请求的处理程序
+func optionsHandler(allow string) HandlerFunc {
+	return func(c *Context) error {
+		c.Response.Header().Set("Allow", allow)
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
 // NotFound 添加 NotFound 处理程序
 func (app *Application) NotFound(handler HandlerFunc) {
 	app.notFoundHandler = handler
 }
 
-// NotFoundHandler 默认 NotFound 处理程序
+// NotFoundHandler 默认 NotFound 处理程序，返回的 404 错误交给错误处理程序，
+// 默认的错误处理程序响应 Not Found，不再直接响应 404 page not found
 func NotFoundHandler() HandlerFunc {
 	return func(c *Context) error {
-		http.Error(c.Response.Writer, "404 page not found", http.StatusNotFound)
-		return nil
+		return NewHTTPError(http.StatusNotFound)
 	}
 }
 
 // handleError 处理错误
 func (app *Application) handleError(c *Context, err error) {
-	if httpError, ok := err.(HTTPError); ok {
+	c.err = err
+	if e, ok := err.(*httpError); ok && !e.custom {
+		app.errorHandler(c, c.statusText(e.Code, e.Message), e.Code)
+	} else if httpError, ok := err.(HTTPError); ok {
 		app.errorHandler(c, httpError.Error(), httpError.Status())
 	} else {
 		app.errorHandler(c, err.Error(), http.StatusInternalServerError)
@@ -178,8 +293,14 @@ func (app *Application) Error(handler ErrorHandlerFunc) {
 }
 
 // ErrorHandler 默认错误处理程序
+//
+// 视图引擎开启 Reload 时，视图的解析和执行错误显示为包含文件、行号和代码片段的调试页面
 func ErrorHandler() ErrorHandlerFunc {
 	return func(c *Context, error string, code int) {
+		var te *TemplateError
+		if errors.As(c.err, &te) && te.debug && te.writePage(c.Response.Writer) == nil {
+			return
+		}
 		http.Error(c.Response.Writer, error, code)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
 )
 
 func TestNew(t *testing.T) {
@@ -31,6 +32,69 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, "POST:ok", res.Body.String())
 }
 
+func TestApplication_AutoOptions(t *testing.T) {
+	r := New()
+	h := func(c *Context) error { return nil }
+	r.GET("/users/{id}", h)
+	r.DELETE("/users/{id}", h)
+
+	req, _ := http.NewRequest("OPTIONS", "/users/1", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "GET, DELETE, OPTIONS", res.Header().Get("Allow"))
+
+	req, _ = http.NewRequest("OPTIONS", "/undefined", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestApplication_AutoOptionsParams(t *testing.T) {
+	r := New()
+	var got []string
+	mw := func(c *Context) error {
+		got = append(got, c.Route().Method()+":"+c.Param("id")+":"+c.Param("section")+":"+c.Param("slug"))
+		return c.Next()
+	}
+	h := func(c *Context) error { return nil }
+	r.GET("/g/{id}", mw, h)
+	r.POST("/{section}/{slug}", mw, h)
+
+	req, _ := http.NewRequest("OPTIONS", "/g/5", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "GET, POST, OPTIONS", res.Header().Get("Allow"))
+
+	req, _ = http.NewRequest("OPTIONS", "/g/5", nil)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"GET:5::", "POST::g:5"}, got)
+}
+
+func TestApplication_NotFound(t *testing.T) {
+	r := New()
+	r.GET("/users", func(c *Context) error { return nil })
+
+	req, _ := http.NewRequest("GET", "/undefined", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, "Not Found\n", res.Body.String())
+
+	// 默认的 404 交给错误处理程序
+	r.Error(func(c *Context, error string, code int) {
+		_, _ = c.WriteWithStatus(code, []byte("error: "+error))
+	})
+	req, _ = http.NewRequest("GET", "/undefined", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, "error: Not Found", res.Body.String())
+}
+
 func TestApplication_URL(t *testing.T) {
 	r := New()
 
@@ -161,3 +225,29 @@ func TestViewAndLayout(t *testing.T) {
 	r.ServeHTTP(res, req)
 	assert.Equal(t, `<main id="other"><h1>Hello, bar</h1></main>`, res.Body.String())
 }
+
+func TestApplication_RenderToString(t *testing.T) {
+	r := New()
+	_, err := r.RenderToString("mail.html", "", nil)
+	assert.NotNil(t, err)
+
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`<body>{{ content }}</body>`)},
+		"mail.html":   {Data: []byte(`<a href="{{ route "user" "id" .id }}">{{ .name }}</a>{{ csrfToken }}{{ len flashes }}`)},
+		"error.html":  {Data: []byte(`{{ .name.foo }}`)},
+	}
+	view := HTMLFS(fsys, ".", ".html")
+	view.Reload(true)
+	_ = r.RegisterView(view)
+	r.GET("/users/{id}", func(c *Context) error { return nil }).Name("user")
+
+	s, err := r.RenderToString("mail.html", "layout.html", Map{"id": 1, "name": "<b>"})
+	assert.Nil(t, err)
+	assert.Equal(t, `<body><a href="/users/1">&lt;b&gt;</a>0</body>`, s)
+
+	// 开启 Reload 时也返回错误，而不是调试页面
+	_, err = r.RenderToString("error.html", "", Map{"name": "a"})
+	assert.NotNil(t, err)
+	_, ok := err.(*TemplateError)
+	assert.True(t, ok)
+}
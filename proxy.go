@@ -0,0 +1,231 @@
+package potgo
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetTrustedProxies 设置受信任的代理服务器，参数为 CIDR 或 IP 地址，例如 10.0.0.0/8、127.0.0.1
+//
+// 只有请求来自受信任的代理时，才会使用 Forwarded、X-Forwarded-For、X-Real-Ip、
+// X-Forwarded-Proto 和 X-Forwarded-Host 请求头，默认不信任任何代理
+func (app *Application) SetTrustedProxies(cidrs ...string) error {
+	proxies := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return fmt.Errorf("potgo: invalid trusted proxy %q", cidr)
+			}
+			if ip.To4() != nil {
+				cidr += "/32"
+			} else {
+				cidr += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("potgo: invalid trusted proxy %q", cidr)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	app.trustedProxies = proxies
+	return nil
+}
+
+// isTrustedProxy 判断 IP 是否为受信任的代理
+func (app *Application) isTrustedProxy(ip net.IP) bool {
+	if app == nil || ip == nil {
+		return false
+	}
+	for _, ipNet := range app.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHop 转发链中的一跳
+type forwardedHop struct {
+	ip    string
+	proto string
+	host  string
+}
+
+// forwardedClient 从右向左遍历转发链，跳过受信任的代理，返回第一个不受信任的一跳，
+// trusted 表示请求是否来自受信任的代理
+func (c *Context) forwardedClient() (client *forwardedHop, trusted bool) {
+	if c.app == nil || len(c.app.trustedProxies) == 0 {
+		return nil, false
+	}
+	if !c.app.isTrustedProxy(net.ParseIP(remoteIP(c.Request.RemoteAddr))) {
+		return nil, false
+	}
+
+	var hops []forwardedHop
+	if values := c.Request.Header.Values("Forwarded"); len(values) > 0 {
+		hops = parseForwarded(strings.Join(values, ","))
+	} else if values := c.Request.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, ip := range strings.Split(strings.Join(values, ","), ",") {
+			hops = append(hops, forwardedHop{ip: strings.TrimSpace(ip)})
+		}
+	} else if ip := strings.TrimSpace(c.Request.Header.Get("X-Real-Ip")); ip != "" {
+		hops = append(hops, forwardedHop{ip: ip})
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i].ip)
+		if ip == nil {
+			// 无法识别的地址，之前的一跳是最后一个可信的地址
+			break
+		}
+		client = &hops[i]
+		if !c.app.isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client, true
+}
+
+// parseForwarded 解析 RFC 7239 Forwarded 请求头
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func parseForwarded(header string) []forwardedHop {
+	var hops []forwardedHop
+	for _, element := range splitQuoted(header, ',') {
+		var hop forwardedHop
+		for _, pair := range splitQuoted(element, ';') {
+			eq := strings.IndexByte(pair, '=')
+			if eq < 0 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:eq]))
+			value := strings.Trim(strings.TrimSpace(pair[eq+1:]), `"`)
+			switch key {
+			case "for":
+				hop.ip = forwardedNodeIP(value)
+			case "proto":
+				hop.proto = strings.ToLower(value)
+			case "host":
+				hop.host = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// forwardedNodeIP 去掉节点的端口和 IPv6 的方括号，unknown 和混淆的标识符返回原值，之后解析 IP 时会失败
+func forwardedNodeIP(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if colon := strings.IndexByte(node, ':'); colon >= 0 && strings.Count(node, ":") == 1 {
+		return node[:colon]
+	}
+	return node
+}
+
+// splitQuoted 使用 sep 分割字符串，忽略引号中的分隔符
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			if quoted {
+				i++
+			}
+		case sep:
+			if !quoted {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// lastHeaderValue 返回以逗号分隔的请求头中最后一个值，即最近的代理添加的值
+func lastHeaderValue(header http.Header, name string) string {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return ""
+	}
+	v := values[len(values)-1]
+	if comma := strings.LastIndexByte(v, ','); comma >= 0 {
+		v = v[comma+1:]
+	}
+	return strings.TrimSpace(v)
+}
+
+// remoteIP 去掉 RemoteAddr 中的端口
+func remoteIP(addr string) string {
+	addr = strings.TrimSpace(addr)
+	// 存在冒号才使用 SplitHostPort，不然会出错
+	if colon := strings.LastIndex(addr, ":"); colon != -1 {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return host
+		}
+	}
+	return addr
+}
+
+// ClientIP 返回用户 IP
+//
+// 请求来自 SetTrustedProxies 设置的受信任代理时，从右向左遍历 Forwarded 或 X-Forwarded-For，
+// 返回第一个不受信任的地址，否则返回 RemoteAddr
+func (c *Context) ClientIP() string {
+	c.checkReleased()
+	if hop, _ := c.forwardedClient(); hop != nil {
+		return hop.ip
+	}
+	return remoteIP(c.Request.RemoteAddr)
+}
+
+// Scheme 返回请求的协议 http 或 https，请求来自受信任的代理时使用 Forwarded 或 X-Forwarded-Proto
+func (c *Context) Scheme() string {
+	if hop, trusted := c.forwardedClient(); trusted {
+		var proto string
+		if hop != nil {
+			proto = hop.proto
+		}
+		if proto == "" && c.Request.Header.Get("Forwarded") == "" {
+			proto = strings.ToLower(lastHeaderValue(c.Request.Header, "X-Forwarded-Proto"))
+		}
+		if proto == "http" || proto == "https" {
+			return proto
+		}
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host 返回请求的主机名，可能包含端口，请求来自受信任的代理时使用 Forwarded 或 X-Forwarded-Host
+func (c *Context) Host() string {
+	if hop, trusted := c.forwardedClient(); trusted {
+		var host string
+		if hop != nil {
+			host = hop.host
+		}
+		if host == "" && c.Request.Header.Get("Forwarded") == "" {
+			host = lastHeaderValue(c.Request.Header, "X-Forwarded-Host")
+		}
+		if host != "" && !strings.ContainsAny(host, " /\\\"") {
+			return host
+		}
+	}
+	return c.Request.Host
+}
@@ -0,0 +1,100 @@
+package potgo
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProxyTestContext(t *testing.T, remoteAddr string, header http.Header) *Context {
+	app := New()
+	assert.Nil(t, app.SetTrustedProxies("10.0.0.0/8", "::1"))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header = header
+
+	c := &Context{app: app}
+	c.reset(httptest.NewRecorder(), req)
+	return c
+}
+
+func TestApplication_SetTrustedProxies(t *testing.T) {
+	app := New()
+	assert.Nil(t, app.SetTrustedProxies("10.0.0.0/8", "127.0.0.1", "::1", "fd00::/8"))
+	assert.Len(t, app.trustedProxies, 4)
+	assert.NotNil(t, app.SetTrustedProxies("10.0.0.0/33"))
+	assert.NotNil(t, app.SetTrustedProxies("localhost"))
+}
+
+func TestContext_ClientIPTrustedProxies(t *testing.T) {
+	tests := []struct {
+		remote string
+		header http.Header
+		ip     string
+	}{
+		// 不受信任的请求来源
+		{"203.0.113.1:1234", http.Header{"X-Forwarded-For": {"1.1.1.1"}}, "203.0.113.1"},
+		// 从右向左跳过受信任的代理，伪造的地址被忽略
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4, 10.0.0.2"}}, "1.2.3.4"},
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4", "10.0.0.2"}}, "1.2.3.4"},
+		// 全部是受信任的代理时返回最左边的地址
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3"},
+		// 无法识别的地址
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"1.2.3.4, garbage, 10.0.0.2"}}, "10.0.0.2"},
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"garbage"}}, "10.0.0.1"},
+		{"10.0.0.1:1234", http.Header{"X-Real-Ip": {"1.2.3.4"}}, "1.2.3.4"},
+		{"[::1]:1234", http.Header{"X-Forwarded-For": {"2001:db8::1"}}, "2001:db8::1"},
+		// RFC 7239
+		{"10.0.0.1:1234", http.Header{
+			"Forwarded":       {`for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.2:80`},
+			"X-Forwarded-For": {"9.9.9.9"},
+		}, "2001:db8:cafe::17"},
+		{"10.0.0.1:1234", http.Header{"Forwarded": {`for=1.2.3.4, for=unknown`}}, "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		c := newProxyTestContext(t, tt.remote, tt.header)
+		assert.Equal(t, tt.ip, c.ClientIP(), tt.header)
+	}
+}
+
+func TestContext_SchemeAndHost(t *testing.T) {
+	c := newProxyTestContext(t, "203.0.113.1:1234", http.Header{
+		"X-Forwarded-Proto": {"https"},
+		"X-Forwarded-Host":  {"evil.com"},
+	})
+	c.Request.Host = "example.com"
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.com", c.Host())
+
+	c.Request.TLS = &tls.ConnectionState{}
+	assert.Equal(t, "https", c.Scheme())
+
+	c = newProxyTestContext(t, "10.0.0.1:1234", http.Header{
+		"X-Forwarded-Proto": {"http, https"},
+		"X-Forwarded-Host":  {"example.org"},
+	})
+	c.Request.Host = "backend:8080"
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "example.org", c.Host())
+
+	c = newProxyTestContext(t, "10.0.0.1:1234", http.Header{
+		"Forwarded":         {`for=1.2.3.4;proto=https;host="example.net:8443", for=10.0.0.2;proto=http;host=internal`},
+		"X-Forwarded-Proto": {"http"},
+	})
+	assert.Equal(t, "https", c.Scheme())
+	assert.Equal(t, "example.net:8443", c.Host())
+
+	// 无效的值被忽略
+	c = newProxyTestContext(t, "10.0.0.1:1234", http.Header{
+		"X-Forwarded-Proto": {"javascript"},
+		"X-Forwarded-Host":  {"a b"},
+	})
+	c.Request.Host = "example.com"
+	assert.Equal(t, "http", c.Scheme())
+	assert.Equal(t, "example.com", c.Host())
+}
@@ -16,6 +16,7 @@ type Response struct {
 	Writer http.ResponseWriter
 	status int
 	size   int
+	after  []func()
 }
 
 // reset 重置 Response
@@ -39,6 +40,45 @@ func (res *Response) Reset(w http.ResponseWriter) bool {
 	return true
 }
 
+// Header 返回响应头，实现 http.ResponseWriter 接口
+func (res *Response) Header() http.Header {
+	return res.Writer.Header()
+}
+
+// Before 注册在向客户端发送响应头之前执行的函数
+//
+// 无论是通过 Response 还是直接通过 Response.Writer 发送响应头，注册的函数都会按注册顺序执行一次，
+// 可以在其中修改响应头，例如设置 cookie
+func (res *Response) Before(fn func()) {
+	if hw, ok := res.Writer.(*hookWriter); ok {
+		hw.before = append(hw.before, fn)
+		return
+	}
+	res.Writer = &hookWriter{ResponseWriter: res.Writer, before: []func(){fn}}
+}
+
+// runBefore 执行 Before 注册的函数，如果已经执行过则忽略
+func (res *Response) runBefore() {
+	if hw, ok := res.Writer.(*hookWriter); ok {
+		hw.runBefore()
+	}
+}
+
+// After 注册在请求处理结束后执行的函数，在错误处理程序写入响应之后按注册顺序执行，
+// 例如关闭包装 Response.Writer 的压缩器
+func (res *Response) After(fn func()) {
+	res.after = append(res.after, fn)
+}
+
+// runAfter 执行 After 注册的函数
+func (res *Response) runAfter() {
+	after := res.after
+	res.after = nil
+	for _, fn := range after {
+		fn()
+	}
+}
+
 // Size 返回写入数据的大小
 func (res *Response) Size() int {
 	return res.size
@@ -56,6 +96,11 @@ func (res *Response) WriteHeader(code int) {
 	}
 }
 
+// WriteHeaderNow 立即向客户端发送响应头
+func (res *Response) WriteHeaderNow() {
+	res.tryWriteHeader()
+}
+
 // Written 是否已经向客户端写入数据
 func (res *Response) Written() bool {
 	return res.size > noWritten
@@ -90,3 +135,74 @@ func (res *Response) Flush() {
 func (res *Response) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return res.Writer.(http.Hijacker).Hijack()
 }
+
+// hookWriter 在发送响应头之前执行注册的函数
+type hookWriter struct {
+	http.ResponseWriter
+	before      []func()
+	wroteHeader bool
+}
+
+func (w *hookWriter) runBefore() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	for _, fn := range w.before {
+		fn()
+	}
+}
+
+func (w *hookWriter) WriteHeader(code int) {
+	w.runBefore()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *hookWriter) Write(b []byte) (int, error) {
+	w.runBefore()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *hookWriter) Flush() {
+	w.runBefore()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *hookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// Unwrap 返回原始的 http.ResponseWriter
+func (w *hookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// copiedWriter Context 副本使用的 http.ResponseWriter，拒绝写入数据
+type copiedWriter struct{}
+
+func (copiedWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (copiedWriter) Write([]byte) (int, error) {
+	return 0, errContextCopied
+}
+
+func (copiedWriter) WriteHeader(int) {}
+
+// releasedWriter 已释放的 Context 使用的 http.ResponseWriter，任何操作都会 panic
+type releasedWriter struct{}
+
+func (releasedWriter) Header() http.Header {
+	panic(errContextReleased)
+}
+
+func (releasedWriter) Write([]byte) (int, error) {
+	panic(errContextReleased)
+}
+
+func (releasedWriter) WriteHeader(int) {
+	panic(errContextReleased)
+}
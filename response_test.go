@@ -1,6 +1,7 @@
 package potgo
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -64,3 +65,44 @@ func TestResponse_Reset(t *testing.T) {
 
 	assert.False(t, n)
 }
+
+func TestResponse_Before(t *testing.T) {
+	rec := httptest.NewRecorder()
+	res := &Response{}
+	res.reset(rec)
+
+	calls := 0
+	res.Before(func() {
+		calls++
+		res.Header().Set("X-Before", "1")
+	})
+
+	// 直接通过 Writer 写入也会执行
+	req, _ := http.NewRequest("POST", "/test", nil)
+	http.Redirect(res.Writer, req, "/", http.StatusFound)
+	_, _ = res.Write([]byte("foo"))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "1", rec.Header().Get("X-Before"))
+	assert.Equal(t, http.StatusFound, rec.Code)
+}
+
+func TestResponse_After(t *testing.T) {
+	app := New()
+	app.Error(func(c *Context, message string, code int) {
+		_, _ = c.Write([]byte("error;"))
+	})
+	app.GET("/test", func(c *Context) error {
+		c.Response.After(func() {
+			_, _ = c.Write([]byte("after"))
+		})
+		return errors.New("failed")
+	})
+
+	req, _ := http.NewRequest("GET", "/test", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+
+	// 在错误处理程序之后执行
+	assert.Equal(t, "error;after", res.Body.String())
+}
@@ -28,6 +28,21 @@ func (r *Route) Name(name string) {
 	r.name = name
 }
 
+// GetName 返回路由名称
+func (r *Route) GetName() string {
+	return r.name
+}
+
+// Method 返回路由的 HTTP 方法
+func (r *Route) Method() string {
+	return r.method
+}
+
+// Path 返回路由的路径模板，例如 /user/:id
+func (r *Route) Path() string {
+	return r.path
+}
+
 func (r *Route) buildPathTemplate(path string) {
 	r.maxParams = 0
 
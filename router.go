@@ -1,7 +1,9 @@
 package potgo
 
 import (
+	"io/fs"
 	"net/http"
+	"os"
 	"path"
 	"strings"
 )
@@ -98,19 +100,30 @@ func (r *Router) Static(relativePath, root string) {
 	if root == "" {
 		root = "."
 	}
+	r.StaticFS(relativePath, os.DirFS(root))
+}
+
+// StaticFS 使用 fs.FS 提供静态文件，例如 embed.FS
+//
+//	//go:embed public
+//	var public embed.FS
+//
+//	sub, _ := fs.Sub(public, "public")
+//	router.StaticFS("/static", sub)
+func (r *Router) StaticFS(relativePath string, fsys fs.FS) {
 	prefix := path.Join(r.prefix, relativePath)
-	fs := http.Dir(root)
-	fileServer := http.StripPrefix(prefix, http.FileServer(fs))
+	fileServer := http.StripPrefix(prefix, http.FileServer(http.FS(fsys)))
 	urlPattern := path.Join(prefix, "/{filepath:*}")
 
 	r.GET(urlPattern, func(c *Context) error {
-		file := c.Param("filepath")
+		file := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+		if file == "" {
+			file = "."
+		}
 
-		f, err := fs.Open(file)
-		if err != nil {
+		if _, err := fs.Stat(fsys, file); err != nil {
 			return r.app.notFoundHandler(c)
 		}
-		_ = f.Close()
 
 		fileServer.ServeHTTP(c.Response.Writer, c.Request)
 		return nil
@@ -120,8 +133,14 @@ func (r *Router) Static(relativePath, root string) {
 // File 输出指定的文件
 func (r *Router) File(relativePath, filepath string) {
 	r.GET(relativePath, func(c *Context) error {
-		c.File(filepath)
-		return nil
+		return c.File(filepath)
+	})
+}
+
+// FileFS 输出 fs.FS 中指定的文件
+func (r *Router) FileFS(relativePath string, fsys fs.FS, name string) {
+	r.GET(relativePath, func(c *Context) error {
+		return c.FileFS(fsys, name)
 	})
 }
 
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestRouter_Use(t *testing.T) {
@@ -111,3 +113,66 @@ func TestRouter_Static(t *testing.T) {
 	r.ServeHTTP(res, req)
 	assert.Equal(t, http.StatusOK, res.Code)
 }
+
+func TestRouter_StaticFS(t *testing.T) {
+	modTime := time.Date(2020, 9, 15, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"css/style.css": {Data: []byte("body {}"), ModTime: modTime},
+		"robots.txt":    {Data: []byte("User-agent: *"), ModTime: modTime},
+	}
+
+	r := New()
+	r.StaticFS("/static", fsys)
+	r.FileFS("/robots.txt", fsys, "robots.txt")
+
+	req, _ := http.NewRequest("GET", "/static/css/style.css", nil)
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "body {}", res.Body.String())
+	assert.Equal(t, "Tue, 15 Sep 2020 12:00:00 GMT", res.Header().Get("Last-Modified"))
+
+	req.Header.Set("If-Modified-Since", "Tue, 15 Sep 2020 12:00:00 GMT")
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+
+	for _, p := range []string{"/static/common.js", "/static/../robots.txt.bak", "/static/css/../../robots.txt.bak"} {
+		req, _ = http.NewRequest("GET", p, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Code, p)
+	}
+
+	req, _ = http.NewRequest("GET", "/robots.txt", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "User-agent: *", res.Body.String())
+	assert.Equal(t, "Tue, 15 Sep 2020 12:00:00 GMT", res.Header().Get("Last-Modified"))
+}
+
+func TestContext_FileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"docs/a.txt": {Data: []byte("0123456789")},
+	}
+
+	r := New()
+	r.GET("/files/{name:*}", func(c *Context) error {
+		return c.FileFS(fsys, "docs/"+c.Param("name"))
+	})
+
+	req, _ := http.NewRequest("GET", "/files/a.txt", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusPartialContent, res.Code)
+	assert.Equal(t, "234", res.Body.String())
+
+	for _, p := range []string{"/files/b.txt", "/files/../docs"} {
+		req, _ = http.NewRequest("GET", p, nil)
+		res = httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusNotFound, res.Code, p)
+	}
+}
@@ -0,0 +1,35 @@
+package potgo
+
+// SessionKey 会话在上下文中保存使用的键
+var SessionKey = NewKey("potgo.session")
+
+// Session 会话接口，由 sessions 包实现
+type Session interface {
+	// ID 返回会话 ID
+	ID() string
+	// Get 获取会话数据
+	Get(key string) interface{}
+	// Set 设置会话数据
+	Set(key string, value interface{})
+	// Delete 删除会话数据
+	Delete(key string)
+	// Clear 清空会话数据
+	Clear()
+	// Regenerate 重新生成会话 ID，用户登录后应该调用以防止会话固定攻击
+	Regenerate() error
+	// Destroy 销毁会话
+	Destroy() error
+	// AddFlash 添加闪存数据，闪存数据读取一次后自动删除
+	AddFlash(value interface{}, kind ...string)
+	// Flashes 读取并删除闪存数据
+	Flashes(kind ...string) []interface{}
+}
+
+// Session 返回当前请求的会话
+func (c *Context) Session() Session {
+	value, ok := SessionKey.Get(c)
+	if !ok {
+		panic("potgo: session is missing, pls use `sessions.Middleware`")
+	}
+	return value.(Session)
+}
@@ -0,0 +1,101 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/icodechef/potgo"
+)
+
+// maxCookieSize 浏览器允许的 cookie 最大长度
+const maxCookieSize = 4096
+
+// cookieCodecName 签名中使用的名称，会话数据不能用作其它 cookie 的值
+const cookieCodecName = "potgo.session"
+
+var (
+	errValueTooLong = errors.New("sessions: cookie value is too long")
+	errInvalidKey   = errors.New("sessions: hash key must not be empty")
+)
+
+// CookieStore 将会话数据签名并加密后保存在 cookie 中，使用 potgo.CookieCodec 编码
+type CookieStore struct {
+	codecs []*potgo.CookieCodec
+}
+
+type cookieSession struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+var _ Store = &CookieStore{}
+
+// NewCookieStore 创建 cookie 会话存储
+//
+// keyPairs 由签名密钥和加密密钥成对组成，加密密钥可以为 nil 表示只签名不加密，
+// 加密密钥的长度必须为 16、24 或 32 字节，分别对应 AES-128、AES-192 和 AES-256。
+// 传入多对密钥时使用第一对密钥编码，解码时依次尝试所有密钥，从而实现密钥轮换
+//
+//	sessions.NewCookieStore([]byte("new-hash-key"), []byte("new-block-key-16"),
+//		[]byte("old-hash-key"), []byte("old-block-key-16"))
+func NewCookieStore(keyPairs ...[]byte) (*CookieStore, error) {
+	s := &CookieStore{}
+	for i := 0; i < len(keyPairs); i += 2 {
+		var blockKey []byte
+		if i+1 < len(keyPairs) {
+			blockKey = keyPairs[i+1]
+		}
+		c, err := potgo.NewCookieCodec(keyPairs[i], blockKey)
+		if err != nil {
+			return nil, err
+		}
+		s.codecs = append(s.codecs, c)
+	}
+	if len(s.codecs) == 0 {
+		return nil, errInvalidKey
+	}
+	return s, nil
+}
+
+// Load 解码 cookie 中的会话数据
+func (s *CookieStore) Load(value string) (string, map[string]interface{}, error) {
+	for _, c := range s.codecs {
+		b, err := c.Decode(cookieCodecName, value, c.CanEncrypt())
+		if err != nil {
+			continue
+		}
+
+		var sess cookieSession
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&sess); err != nil {
+			return "", nil, err
+		}
+		return sess.ID, sess.Values, nil
+	}
+	return "", nil, ErrNotFound
+}
+
+// Save 编码会话数据
+func (s *CookieStore) Save(id string, values map[string]interface{}, maxAge int) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(&cookieSession{ID: id, Values: values}); err != nil {
+		return "", err
+	}
+
+	c := s.codecs[0]
+	expires := time.Now().Add(time.Duration(maxAge) * time.Second).Unix()
+	value, err := c.Encode(cookieCodecName, buf.Bytes(), expires, c.CanEncrypt())
+	if err != nil {
+		return "", err
+	}
+	if len(value) > maxCookieSize {
+		return "", errValueTooLong
+	}
+	return value, nil
+}
+
+// Delete 会话数据保存在 cookie 中，无需删除
+func (s *CookieStore) Delete(id string) error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package sessions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieStore(t *testing.T) {
+	store, err := NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	assert.Nil(t, err)
+
+	value, err := store.Save("abc", map[string]interface{}{"name": "foo"}, 60)
+	assert.Nil(t, err)
+	assert.NotContains(t, value, "foo")
+
+	id, values, err := store.Load(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", id)
+	assert.Equal(t, "foo", values["name"])
+
+	// 篡改
+	_, _, err = store.Load(value[:len(value)-2] + "xx")
+	assert.Equal(t, ErrNotFound, err)
+
+	// 过期
+	value, _ = store.Save("abc", map[string]interface{}{"name": "foo"}, -1)
+	_, _, err = store.Load(value)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestCookieStore_KeyRotation(t *testing.T) {
+	old, _ := NewCookieStore([]byte("old-hash-key"), []byte("old-block-key-16"))
+	value, _ := old.Save("abc", map[string]interface{}{"name": "foo"}, 60)
+
+	store, err := NewCookieStore([]byte("new-hash-key"), []byte("new-block-key-16"),
+		[]byte("old-hash-key"), []byte("old-block-key-16"))
+	assert.Nil(t, err)
+
+	_, values, err := store.Load(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", values["name"])
+
+	other, _ := NewCookieStore([]byte("new-hash-key"), []byte("new-block-key-16"))
+	_, _, err = other.Load(value)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestNewCookieStore(t *testing.T) {
+	_, err := NewCookieStore()
+	assert.NotNil(t, err)
+
+	_, err = NewCookieStore([]byte("hash-key"), []byte("short"))
+	assert.NotNil(t, err)
+
+	_, err = NewCookieStore([]byte("hash-key"))
+	assert.Nil(t, err)
+}
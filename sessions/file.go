@@ -0,0 +1,126 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const filePrefix = "sess_"
+
+// FileStore 文件会话存储，每个会话保存为目录下的一个文件
+type FileStore struct {
+	mu  sync.RWMutex
+	dir string
+}
+
+type fileSession struct {
+	Expires time.Time
+	Values  map[string]interface{}
+}
+
+var _ Store = &FileStore{}
+
+// NewFileStore 创建文件会话存储，目录不存在时自动创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) filename(id string) string {
+	return filepath.Join(s.dir, filePrefix+id)
+}
+
+// Load 加载会话
+func (s *FileStore) Load(value string) (string, map[string]interface{}, error) {
+	if !validID(value) {
+		return "", nil, ErrNotFound
+	}
+
+	s.mu.RLock()
+	b, err := ioutil.ReadFile(s.filename(value))
+	s.mu.RUnlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+
+	var sess fileSession
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&sess); err != nil {
+		return "", nil, err
+	}
+	if time.Now().After(sess.Expires) {
+		return "", nil, ErrNotFound
+	}
+	return value, sess.Values, nil
+}
+
+// Save 保存会话
+func (s *FileStore) Save(id string, values map[string]interface{}, maxAge int) (string, error) {
+	if !validID(id) {
+		return "", ErrNotFound
+	}
+
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(&fileSession{
+		Expires: time.Now().Add(time.Duration(maxAge) * time.Second),
+		Values:  values,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 先写入临时文件再重命名，避免读取到写了一半的文件
+	tmp := s.filename(id) + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return "", err
+	}
+	return id, os.Rename(tmp, s.filename(id))
+}
+
+// Delete 删除会话
+func (s *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.filename(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GC 删除已过期的会话文件
+func (s *FileStore) GC() error {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, filePrefix) || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		id := strings.TrimPrefix(name, filePrefix)
+		if _, _, err := s.Load(id); err == ErrNotFound {
+			if err := s.Delete(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
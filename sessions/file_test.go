@@ -0,0 +1,49 @@
+package sessions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "potgo_sessions")
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileStore(dir)
+	assert.Nil(t, err)
+
+	_, err = store.Save("abc", map[string]interface{}{"name": "foo"}, 60)
+	assert.Nil(t, err)
+
+	id, values, err := store.Load("abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", id)
+	assert.Equal(t, "foo", values["name"])
+
+	_, _, err = store.Load("../abc")
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, store.Delete("abc"))
+	_, _, err = store.Load("abc")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestFileStore_GC(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "potgo_sessions")
+	defer os.RemoveAll(dir)
+
+	store, _ := NewFileStore(dir)
+	_, _ = store.Save("abc", map[string]interface{}{}, -1)
+	_, _ = store.Save("def", map[string]interface{}{}, 60)
+
+	assert.Nil(t, store.GC())
+
+	_, err := os.Stat(filepath.Join(dir, "sess_abc"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "sess_def"))
+	assert.Nil(t, err)
+}
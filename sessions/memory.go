@@ -0,0 +1,108 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore 内存会话存储，过期的会话会定期清理
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]memorySession
+	done     chan struct{}
+	once     sync.Once
+}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore 创建内存会话存储，interval 为清理过期会话的间隔，默认为 1 分钟
+func NewMemoryStore(interval ...time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		sessions: make(map[string]memorySession),
+		done:     make(chan struct{}),
+	}
+
+	d := time.Minute
+	if len(interval) > 0 && interval[0] > 0 {
+		d = interval[0]
+	}
+	go s.sweep(d)
+
+	return s
+}
+
+// Load 加载会话
+func (s *MemoryStore) Load(value string) (string, map[string]interface{}, error) {
+	s.mu.RLock()
+	sess, ok := s.sessions[value]
+	s.mu.RUnlock()
+
+	if !ok || time.Now().After(sess.expires) {
+		return "", nil, ErrNotFound
+	}
+	return value, copyValues(sess.values), nil
+}
+
+// Save 保存会话
+func (s *MemoryStore) Save(id string, values map[string]interface{}, maxAge int) (string, error) {
+	s.mu.Lock()
+	s.sessions[id] = memorySession{
+		values:  copyValues(values),
+		expires: time.Now().Add(time.Duration(maxAge) * time.Second),
+	}
+	s.mu.Unlock()
+	return id, nil
+}
+
+// Delete 删除会话
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Len 返回会话数量
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.sessions)
+}
+
+// Close 停止清理过期会话
+func (s *MemoryStore) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// sweep 定期清理过期会话
+func (s *MemoryStore) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.removeExpired()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) removeExpired() {
+	now := time.Now()
+	s.mu.Lock()
+	for id, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, id)
+		}
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,44 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+
+	_, _, err := store.Load("undefined")
+	assert.Equal(t, ErrNotFound, err)
+
+	values := map[string]interface{}{"name": "foo"}
+	value, err := store.Save("abc", values, 60)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", value)
+
+	// 保存后修改不影响存储中的数据
+	values["name"] = "bar"
+	id, loaded, err := store.Load("abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", id)
+	assert.Equal(t, "foo", loaded["name"])
+
+	assert.Nil(t, store.Delete("abc"))
+	_, _, err = store.Load("abc")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore(10 * time.Millisecond)
+	defer store.Close()
+
+	_, _ = store.Save("abc", map[string]interface{}{}, -1)
+	_, _ = store.Save("def", map[string]interface{}{}, 60)
+	assert.Equal(t, 2, store.Len())
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, store.Len())
+}
@@ -0,0 +1,308 @@
+package sessions
+
+import (
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/icodechef/potgo"
+)
+
+// flashPrefix 闪存数据在会话中保存使用的键前缀
+const flashPrefix = "_flash."
+
+// errSessionClosed 会话中间件返回后修改会话
+var errSessionClosed = errors.New("sessions: session is closed")
+
+func init() {
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// Options 会话 cookie 选项，没有设置的选项使用 DefaultOptions 中的值
+type Options struct {
+	Name   string // cookie 名称
+	MaxAge int    // 会话有效期，单位为秒
+	Path   string
+	Domain string
+	Secure bool
+	// DisableHTTPOnly 为 true 时不设置 HttpOnly，允许在 JavaScript 中读取会话 cookie
+	DisableHTTPOnly bool
+	// SameSite 默认为 http.SameSiteLaxMode
+	SameSite http.SameSite
+}
+
+// DefaultOptions 默认的会话 cookie 选项
+var DefaultOptions = Options{
+	Name:     "potgo_session",
+	MaxAge:   86400,
+	Path:     "/",
+	SameSite: http.SameSiteLaxMode,
+}
+
+// Middleware 返回会话中间件
+//
+// 会话在第一次访问时才从存储中加载，只有修改过的会话才会在发送响应头之前保存，
+// 所以必须在向客户端写入数据之前修改会话。
+// 会话数据使用 encoding/gob 编码，保存自定义类型前需要使用 gob.Register 注册。
+// 会话可以在多个 goroutine 中使用，中间件返回后对会话的修改会被忽略，
+// 例如 Timeout 超时后仍在执行的 HandlerFunc 对会话的修改
+func Middleware(store Store, options ...Options) potgo.HandlerFunc {
+	opts := DefaultOptions
+	if len(options) > 0 {
+		opts = options[0]
+		if opts.Name == "" {
+			opts.Name = DefaultOptions.Name
+		}
+		if opts.MaxAge == 0 {
+			opts.MaxAge = DefaultOptions.MaxAge
+		}
+		if opts.Path == "" {
+			opts.Path = DefaultOptions.Path
+		}
+		if opts.SameSite == 0 {
+			opts.SameSite = DefaultOptions.SameSite
+		}
+	}
+
+	return func(c *potgo.Context) error {
+		s := &session{
+			c:       c,
+			store:   store,
+			options: &opts,
+		}
+		potgo.SessionKey.Set(c, s)
+		c.Response.Before(s.save)
+
+		err := c.Next()
+		s.close()
+		if err == nil {
+			err = s.err
+		}
+		return err
+	}
+}
+
+// session 实现 potgo.Session 接口
+type session struct {
+	mu        sync.Mutex
+	c         *potgo.Context
+	store     Store
+	options   *Options
+	id        string
+	oldID     string
+	values    map[string]interface{}
+	loaded    bool
+	dirty     bool
+	destroyed bool
+	closed    bool // 中间件是否已经返回
+	err       error
+}
+
+var _ potgo.Session = &session{}
+
+// load 从存储中加载会话
+func (s *session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	if cookie, err := s.c.Request.Cookie(s.options.Name); err == nil {
+		if id, values, err := s.store.Load(cookie.Value); err == nil {
+			s.id, s.values = id, values
+		}
+	}
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+}
+
+// save 保存修改过的会话并设置 cookie
+func (s *session) save() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveLocked()
+}
+
+// close 保存修改过的会话，之后对会话的修改会被忽略
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saveLocked()
+	s.closed = true
+}
+
+// saveLocked 保存修改过的会话，调用者需要持有锁
+func (s *session) saveLocked() {
+	if !s.dirty || s.err != nil {
+		return
+	}
+	s.dirty = false
+
+	if s.oldID != "" {
+		if s.err = s.store.Delete(s.oldID); s.err != nil {
+			return
+		}
+		s.oldID = ""
+	}
+
+	if s.destroyed && len(s.values) == 0 {
+		s.setCookie("", -1)
+		return
+	}
+
+	if s.id == "" {
+		if s.id, s.err = newID(); s.err != nil {
+			return
+		}
+	}
+
+	var value string
+	if value, s.err = s.store.Save(s.id, s.values, s.options.MaxAge); s.err != nil {
+		return
+	}
+	s.setCookie(value, s.options.MaxAge)
+}
+
+func (s *session) setCookie(value string, maxAge int) {
+	s.c.SetCookie(&http.Cookie{
+		Name:     s.options.Name,
+		Value:    value,
+		Path:     s.options.Path,
+		Domain:   s.options.Domain,
+		MaxAge:   maxAge,
+		Secure:   s.options.Secure,
+		HttpOnly: !s.options.DisableHTTPOnly,
+		SameSite: s.options.SameSite,
+	})
+}
+
+// ID 返回会话 ID
+func (s *session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	if s.id == "" {
+		s.id, s.err = newID()
+		s.dirty = true
+	}
+	return s.id
+}
+
+// Get 获取会话数据
+func (s *session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	return s.values[key]
+}
+
+// Set 设置会话数据
+func (s *session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.load()
+	s.values[key] = value
+	s.dirty = true
+}
+
+// Delete 删除会话数据
+func (s *session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.load()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Clear 清空会话数据
+func (s *session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.load()
+	s.values = make(map[string]interface{})
+	s.dirty = true
+}
+
+// Regenerate 重新生成会话 ID，原会话数据保留
+func (s *session) Regenerate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSessionClosed
+	}
+	s.load()
+	if s.id != "" && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id, s.err = newID()
+	s.dirty = true
+	return s.err
+}
+
+// Destroy 销毁会话，删除存储中的会话数据和 cookie
+func (s *session) Destroy() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errSessionClosed
+	}
+	s.load()
+	if s.id != "" && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = ""
+	s.values = make(map[string]interface{})
+	s.destroyed = true
+	s.dirty = true
+	return nil
+}
+
+// AddFlash 添加闪存数据，kind 为闪存数据的类型，例如 success、error
+func (s *session) AddFlash(value interface{}, kind ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	key := flashKey(kind)
+	s.load()
+	flashes, _ := s.values[key].([]interface{})
+	// 总是创建新的切片，不写入可能与存储共享的底层数组
+	s.values[key] = append(flashes[:len(flashes):len(flashes)], value)
+	s.dirty = true
+}
+
+// Flashes 读取并删除闪存数据
+func (s *session) Flashes(kind ...string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := flashKey(kind)
+	s.load()
+	flashes, ok := s.values[key].([]interface{})
+	if ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+	return flashes
+}
+
+func flashKey(kind []string) string {
+	if len(kind) > 0 {
+		return flashPrefix + kind[0]
+	}
+	return flashPrefix + "default"
+}
@@ -0,0 +1,205 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/icodechef/potgo"
+	"github.com/stretchr/testify/assert"
+)
+
+func doRequest(app *potgo.Application, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func getCookie(res *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == DefaultOptions.Name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	app := potgo.New()
+	app.Use(Middleware(store))
+
+	app.GET("/set", func(c *potgo.Context) error {
+		c.Session().Set("name", "foo")
+		return c.Text("ok")
+	})
+	app.GET("/get", func(c *potgo.Context) error {
+		name, _ := c.Session().Get("name").(string)
+		return c.Text(name)
+	})
+	app.GET("/login", func(c *potgo.Context) error {
+		if err := c.Session().Regenerate(); err != nil {
+			return err
+		}
+		c.Session().AddFlash("welcome", "success")
+		return c.Redirect("/get")
+	})
+	app.GET("/flash", func(c *potgo.Context) error {
+		flashes := c.Session().Flashes("success")
+		if len(flashes) == 0 {
+			return c.Text("")
+		}
+		return c.Text(flashes[0].(string))
+	})
+	app.GET("/logout", func(c *potgo.Context) error {
+		return c.Session().Destroy()
+	})
+	app.GET("/none", func(c *potgo.Context) error {
+		return c.Text("none")
+	})
+
+	// 未使用会话不设置 cookie
+	res := doRequest(app, "/none", nil)
+	assert.Nil(t, getCookie(res))
+
+	res = doRequest(app, "/set", nil)
+	cookie := getCookie(res)
+	assert.NotNil(t, cookie)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, 1, store.Len())
+
+	res = doRequest(app, "/get", cookie)
+	assert.Equal(t, "foo", res.Body.String())
+	assert.Nil(t, getCookie(res))
+
+	// 重新生成会话 ID，原会话被删除
+	res = doRequest(app, "/login", cookie)
+	assert.Equal(t, http.StatusFound, res.Code)
+	newCookie := getCookie(res)
+	assert.NotNil(t, newCookie)
+	assert.NotEqual(t, cookie.Value, newCookie.Value)
+	assert.Equal(t, 1, store.Len())
+
+	res = doRequest(app, "/get", cookie)
+	assert.Equal(t, "", res.Body.String())
+	res = doRequest(app, "/get", newCookie)
+	assert.Equal(t, "foo", res.Body.String())
+
+	// 闪存数据只能读取一次
+	res = doRequest(app, "/flash", newCookie)
+	assert.Equal(t, "welcome", res.Body.String())
+	res = doRequest(app, "/flash", newCookie)
+	assert.Equal(t, "", res.Body.String())
+
+	res = doRequest(app, "/logout", newCookie)
+	assert.Equal(t, -1, getCookie(res).MaxAge)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestMiddleware_CookieStore(t *testing.T) {
+	store, err := NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef"))
+	assert.Nil(t, err)
+	app := potgo.New()
+	app.Use(Middleware(store))
+
+	app.GET("/set", func(c *potgo.Context) error {
+		c.Session().Set("name", "foo")
+		return c.Text("ok")
+	})
+	app.GET("/get", func(c *potgo.Context) error {
+		name, _ := c.Session().Get("name").(string)
+		return c.Text(name)
+	})
+
+	res := doRequest(app, "/set", nil)
+	cookie := getCookie(res)
+	assert.NotNil(t, cookie)
+
+	res = doRequest(app, "/get", cookie)
+	assert.Equal(t, "foo", res.Body.String())
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	app := potgo.New()
+	handler := func(c *potgo.Context) error {
+		c.Session().Set("name", "foo")
+		return nil
+	}
+	app.GET("/partial", Middleware(store, Options{MaxAge: 3600}), handler)
+	app.GET("/script", Middleware(store, Options{DisableHTTPOnly: true, SameSite: http.SameSiteStrictMode}), handler)
+
+	// 只设置部分选项时仍然默认设置 HttpOnly 和 SameSite
+	cookie := getCookie(doRequest(app, "/partial", nil))
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	cookie = getCookie(doRequest(app, "/script", nil))
+	assert.False(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+}
+
+func TestMiddleware_ConcurrentFlashes(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	app := potgo.New()
+	app.Use(Middleware(store))
+
+	var loaded sync.WaitGroup
+	app.GET("/add", func(c *potgo.Context) error {
+		c.Session().AddFlash(c.Query("v"))
+		return nil
+	})
+	app.GET("/add-sync", func(c *potgo.Context) error {
+		// 两个请求都加载会话后再添加闪存数据
+		c.Session().Get("loaded")
+		loaded.Done()
+		loaded.Wait()
+		c.Session().AddFlash(c.Query("v"))
+		return nil
+	})
+	app.GET("/flashes", func(c *potgo.Context) error {
+		return c.Text("%v", c.Session().Flashes())
+	})
+
+	// 闪存数据的切片有剩余容量
+	cookie := getCookie(doRequest(app, "/add?v=1", nil))
+	doRequest(app, "/add?v=2", cookie)
+	doRequest(app, "/add?v=3", cookie)
+
+	// 同一个会话的并发请求不共享闪存数据的底层数组
+	var wg sync.WaitGroup
+	loaded.Add(2)
+	for _, v := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(v string) {
+			defer wg.Done()
+			doRequest(app, "/add-sync?v="+v, cookie)
+		}(v)
+	}
+	wg.Wait()
+
+	body := doRequest(app, "/flashes", cookie).Body.String()
+	// 后保存的请求覆盖先保存的请求
+	assert.Contains(t, []string{"[1 2 3 a]", "[1 2 3 b]"}, body)
+}
+
+func TestContext_SessionMissing(t *testing.T) {
+	app := potgo.New()
+	app.GET("/test", func(c *potgo.Context) error {
+		c.Session()
+		return nil
+	})
+
+	assert.Panics(t, func() {
+		doRequest(app, "/test", nil)
+	})
+}
@@ -0,0 +1,59 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrNotFound 会话不存在或已过期
+var ErrNotFound = errors.New("sessions: session not found")
+
+// Store 会话存储接口
+//
+// Load 根据 cookie 中的值加载会话，Save 保存会话并返回写入 cookie 的值。
+// 服务端存储的 cookie 值为会话 ID，CookieStore 的 cookie 值为加密后的会话数据
+type Store interface {
+	// Load 加载会话，会话不存在或已过期时返回 ErrNotFound
+	Load(value string) (id string, values map[string]interface{}, err error)
+	// Save 保存会话，maxAge 为会话的有效期，单位为秒
+	Save(id string, values map[string]interface{}, maxAge int) (value string, err error)
+	// Delete 删除会话
+	Delete(id string) error
+}
+
+// newID 生成会话 ID
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validID 检查会话 ID 是否合法，防止用于文件路径时出现路径穿越
+func validID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// copyValues 复制会话数据
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		// 闪存数据等切片也要复制，避免多个请求共享底层数组
+		if vs, ok := v.([]interface{}); ok {
+			v = append([]interface{}(nil), vs...)
+		}
+		m[k] = v
+	}
+	return m
+}
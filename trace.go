@@ -0,0 +1,130 @@
+package potgo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// RequestIDKey RequestID 中间件在上下文中保存请求 ID 使用的键
+	RequestIDKey = NewKey("potgo.request_id", "")
+	// TraceContextKey RequestID 中间件在上下文中保存追踪上下文使用的键
+	TraceContextKey = NewKey("potgo.trace_context", (*TraceContext)(nil))
+)
+
+// ErrInvalidTraceParent traceparent 请求头格式错误
+var ErrInvalidTraceParent = errors.New("potgo: invalid traceparent")
+
+// TraceContext W3C Trace Context 追踪上下文
+//
+// TraceID 在整个调用链中保持不变，SpanID 为当前请求新生成的 ID，ParentID 为上游服务的 SpanID
+type TraceContext struct {
+	TraceID  string
+	ParentID string
+	SpanID   string
+	Flags    byte
+	State    string
+}
+
+// NewTraceContext 根据上游的 traceparent 和 tracestate 创建当前请求的追踪上下文，
+// traceparent 无效时开始新的追踪
+func NewTraceContext(traceparent, tracestate string) *TraceContext {
+	tc, err := ParseTraceParent(traceparent)
+	if err != nil {
+		return &TraceContext{
+			TraceID: randomHex(16),
+			SpanID:  randomHex(8),
+			Flags:   1,
+		}
+	}
+	tc.ParentID, tc.SpanID = tc.SpanID, randomHex(8)
+	tc.State = strings.TrimSpace(tracestate)
+	return tc
+}
+
+// ParseTraceParent 解析 traceparent 请求头，返回的 SpanID 为上游服务的 SpanID
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(s string) (*TraceContext, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.Split(s, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return nil, ErrInvalidTraceParent
+	}
+	// 版本 00 只有 4 个字段，ff 是无效的版本
+	if (parts[0] == "00" && len(parts) != 4) || parts[0] == "ff" {
+		return nil, ErrInvalidTraceParent
+	}
+	for _, p := range parts[:4] {
+		if !isLowerHex(p) {
+			return nil, ErrInvalidTraceParent
+		}
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return nil, ErrInvalidTraceParent
+	}
+
+	flags, _ := hex.DecodeString(parts[3])
+	return &TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		Flags:   flags[0],
+	}, nil
+}
+
+// Sampled 是否设置了采样标记
+func (tc *TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// TraceParent 返回传递给下游服务的 traceparent
+func (tc *TraceContext) TraceParent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+// Inject 将 traceparent 和 tracestate 写入下游请求的请求头
+//
+//	req, _ := http.NewRequest("GET", "http://backend/api", nil)
+//	c.TraceContext().Inject(req.Header)
+func (tc *TraceContext) Inject(header http.Header) {
+	header.Set("traceparent", tc.TraceParent())
+	if tc.State != "" {
+		header.Set("tracestate", tc.State)
+	} else {
+		header.Del("tracestate")
+	}
+}
+
+// RequestID 返回当前请求的 ID，没有使用 RequestID 中间件时返回空字符串
+func (c *Context) RequestID() string {
+	id, _ := RequestIDKey.Get(c)
+	s, _ := id.(string)
+	return s
+}
+
+// TraceContext 返回当前请求的追踪上下文，没有使用 RequestID 中间件时返回 nil
+func (c *Context) TraceContext() *TraceContext {
+	tc, _ := TraceContextKey.Get(c)
+	t, _ := tc.(*TraceContext)
+	return t
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package potgo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.TraceParent())
+
+	// 未来的版本可以包含更多字段
+	tc, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.Nil(t, err)
+	assert.False(t, tc.Sampled())
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		_, err = ParseTraceParent(s)
+		assert.Equal(t, ErrInvalidTraceParent, err, s)
+	}
+}
+
+func TestContext_RequestID(t *testing.T) {
+	c := &Context{}
+	assert.Equal(t, "", c.RequestID())
+	assert.Nil(t, c.TraceContext())
+
+	RequestIDKey.Set(c, "abc")
+	TraceContextKey.Set(c, NewTraceContext("", ""))
+	assert.Equal(t, "abc", c.RequestID())
+	assert.Len(t, c.TraceContext().TraceID, 32)
+}
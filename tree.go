@@ -130,6 +130,7 @@ func (n *node) addRoute(path string, route *Route) {
 type nodeValue struct {
 	handlers []HandlerFunc
 	pKeys    []string
+	route    *Route
 }
 
 func (n *node) getRoute(path string, pValues []string) (value nodeValue) {
@@ -137,6 +138,7 @@ func (n *node) getRoute(path string, pValues []string) (value nodeValue) {
 	if r != nil && r.route != nil {
 		value.handlers = r.route.handlers
 		value.pKeys = r.pKeys
+		value.route = r.route
 	}
 	return
 }
@@ -3,152 +3,696 @@ package potgo
 import (
 	"bytes"
 	"fmt"
+	"html"
 	"html/template"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template/parse"
+	"time"
 )
 
 // NoLayout 不使用视图布局常量
 const NoLayout = "no.layout"
 
 // ViewEngine 视图引擎接口
+//
+// Render 的 *Context 可能不包含请求，例如 Application.RenderToString，
+// 此时可以使用 Context.URL 生成 URL，请求相关的数据为零值
 type ViewEngine interface {
 	Load() error
 	Render(io.Writer, string, string, interface{}, *Context) error
 }
 
-// HTMLEngine HTML 引擎
-type HTMLEngine struct {
-	templates *template.Template
+// ExtensionEngine 可以返回视图文件扩展名的视图引擎，RegisterView 使用扩展名选择视图引擎，
+// 没有实现此接口时扩展名为 .html
+type ExtensionEngine interface {
+	ViewEngine
+	Extension() string
+}
+
+// ContentTypeEngine 可以返回渲染结果内容类型的视图引擎，Context.View 使用此内容类型，
+// 没有实现此接口时为 text/html; charset=utf-8
+type ContentTypeEngine interface {
+	ViewEngine
+	ContentType() string
+}
+
+// LayoutEngine 可以把其它视图引擎渲染的内容放入布局的视图引擎，例如 Markdown 视图使用 HTML 布局
+//
+// content 为已经渲染的结果，布局中使用 content 函数输出，不会再转义
+type LayoutEngine interface {
+	ViewEngine
+	RenderLayout(w io.Writer, layout string, content string, data interface{}, c *Context) error
+}
+
+// defaultContentType 视图引擎默认的内容类型
+const defaultContentType = "text/html; charset=utf-8"
+
+// viewExtension 返回视图引擎的扩展名
+func viewExtension(view ViewEngine) string {
+	if v, ok := view.(ExtensionEngine); ok {
+		return v.Extension()
+	}
+	return ".html"
+}
+
+// viewContentType 返回视图引擎的内容类型
+func viewContentType(view ViewEngine) string {
+	if v, ok := view.(ContentTypeEngine); ok {
+		return v.ContentType()
+	}
+	return defaultContentType
+}
+
+// isHTMLEngine 视图引擎是否输出 HTML
+func isHTMLEngine(view ViewEngine) bool {
+	return strings.HasPrefix(viewContentType(view), "text/html")
+}
+
+// renderLayout 使用布局扩展名对应的视图引擎渲染布局，view 为渲染 content 的视图引擎
+//
+// HTML 布局中只信任明确输出 HTML 的视图引擎渲染的内容，例如 HTMLEngine 和 MarkdownEngine，
+// 其它视图引擎的内容先转义，例如 TextEngine 不会转义输出
+func renderLayout(w io.Writer, view ViewEngine, layout string, content string, data interface{}, c *Context) error {
+	var engine ViewEngine
+	if c != nil && c.app != nil {
+		engine = c.app.views[path.Ext(layout)]
+	}
+	le, ok := engine.(LayoutEngine)
+	if !ok {
+		return fmt.Errorf("layout: no view engine for %s", layout)
+	}
+	if isHTMLEngine(le) && !isTrustedEngine(view) {
+		content = html.EscapeString(content)
+	}
+	return le.RenderLayout(w, layout, content, data, c)
+}
+
+// isTrustedEngine 视图引擎输出的 HTML 是否可以直接放入 HTML 布局，只有实现 ContentTypeEngine 并返回 text/html 时才信任
+func isTrustedEngine(view ViewEngine) bool {
+	v, ok := view.(ContentTypeEngine)
+	return ok && strings.HasPrefix(v.ContentType(), "text/html")
+}
+
+// scanViews 遍历 fsys，返回扩展名为 extension 的视图文件的名称和修改时间
+func scanViews(fsys fs.FS, extension string) (map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(name) != extension {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[name] = info.ModTime()
+		return nil
+	})
+	return files, err
+}
+
+// sortedNames 返回排序后的视图名称
+func sortedNames(files map[string]time.Time) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// viewTemplate html/template 和 text/template 的模板共有的方法
+type viewTemplate interface {
+	Name() string
+	Execute(w io.Writer, data interface{}) error
+}
+
+// viewTemplates 适配 html/template 和 text/template 的模板集合
+type viewTemplates interface {
+	// lookup 返回指定名称的模板，不存在时返回 nil
+	lookup(name string) viewTemplate
+	// parse 解析视图文件，返回文件中所有模板的解析树
+	parse(text string) ([]*parse.Tree, error)
+	addParseTree(name string, tree *parse.Tree) error
+	funcs(funcMap map[string]interface{})
+	clone() (viewTemplates, error)
+}
+
+// templateEngine HTMLEngine 和 TextEngine 共用的视图加载、模板池和布局解析
+//
+// 加载的模板只作为原型，不会被执行。每次渲染从池中取出一份克隆的模板，
+// 克隆的模板绑定了自己的 renderState，因此并发渲染时请求相关的函数不会互相影响
+type templateEngine struct {
+	mu        sync.RWMutex
+	templates viewTemplates
+	sets      *sync.Pool
+	files     map[string]time.Time // 视图文件的修改时间
+	loadErr   error
+	reload    bool
+	fsys      fs.FS
 	path      string
 	extension string
 	left      string
 	right     string
 	layout    string
-	funcMap   template.FuncMap
-}
+	funcMap   map[string]interface{}
+	engine    ViewEngine                                   // 嵌入 templateEngine 的视图引擎
+	newSet    func(name, left, right string) viewTemplates // 创建空的模板集合
 
-var _ ViewEngine = &HTMLEngine{}
+	standardFuncs bool
+}
 
-// HTML 创建 HTML 对象
-func HTML(path string, extension string) *HTMLEngine {
-	return &HTMLEngine{
-		path:      path,
-		extension: extension,
-		left:      "{{",
-		right:     "}}",
-		layout:    "",
-		funcMap:   make(template.FuncMap),
+// init 初始化 templateEngine，engine 为嵌入 templateEngine 的视图引擎
+func (v *templateEngine) init(engine ViewEngine, newSet func(name, left, right string) viewTemplates, fsys fs.FS, root string, extension string) {
+	v.engine = engine
+	v.newSet = newSet
+	v.fsys = fsys
+	v.path = root
+	v.extension = extension
+	v.left, v.right = "{{", "}}"
+	v.funcMap = make(map[string]interface{})
+	if root != "" && root != "." {
+		v.fsys, v.loadErr = fs.Sub(fsys, root)
 	}
 }
 
 // Layout 设置视图布局文件
-func (v *HTMLEngine) Layout(layout string) {
+func (v *templateEngine) Layout(layout string) {
 	v.layout = layout
 }
 
 // Delims 设置视图动作的左右限定符
-func (v *HTMLEngine) Delims(left, right string) {
+func (v *templateEngine) Delims(left, right string) {
 	v.left, v.right = left, right
 }
 
 // AddFunc 添加视图函数
-func (v *HTMLEngine) AddFunc(name string, callable interface{}) {
+func (v *templateEngine) AddFunc(name string, callable interface{}) {
 	v.funcMap[name] = callable
 }
 
-// Func 设置视图函数
-func (v *HTMLEngine) Func(funcMap template.FuncMap) {
-	for name, value := range funcMap {
-		v.funcMap[name] = value
-	}
+// Reload 设置是否在渲染前检查视图文件的修改时间，并重新加载修改过的视图，用于开发环境
+//
+// 开启后，视图的解析和执行错误由默认错误处理程序显示为包含文件、行号和代码片段的调试页面，
+// 使用 Application.Error 设置的错误处理程序时，由该处理程序决定如何显示
+func (v *templateEngine) Reload(enabled bool) {
+	v.reload = enabled
+}
+
+// Extension 返回视图文件的扩展名
+func (v *templateEngine) Extension() string {
+	return v.extension
 }
 
 // Load 加载视图文件下的所有视图文件
-func (v *HTMLEngine) Load() (err error) {
-	v.templates = template.New("").Delims(v.left, v.right).Funcs(v.funcMap).Funcs(template.FuncMap{
-		"content": func() (string, error) {
-			return "", nil
-		},
-		"route": func() (string, error) {
-			return "", nil
-		},
-	})
+func (v *templateEngine) Load() error {
+	files, err := v.scan()
+	if err != nil {
+		return err
+	}
 
-	// 遍历视图目录
-	err = filepath.Walk(v.path, func(path string, info os.FileInfo, err error) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.files = files
+	v.loadErr = v.parse(files)
+	return v.loadErr
+}
+
+// scan 遍历视图目录，返回视图文件的名称和修改时间
+func (v *templateEngine) scan() (map[string]time.Time, error) {
+	if v.fsys == nil { // fs.Sub 失败
+		return nil, v.loadErr
+	}
+	return scanViews(v.fsys, v.extension)
+}
+
+// parse 解析视图文件，成功后替换当前的模板
+func (v *templateEngine) parse(files map[string]time.Time) error {
+	funcs, stateFuncs := v.baseFuncMap(), v.stateFuncMap(new(renderState))
+	templates := v.newSet("", v.left, v.right)
+	templates.funcs(funcs)
+	templates.funcs(stateFuncs)
+
+	parents := make(map[string]string)
+	for _, name := range sortedNames(files) {
+		b, err := fs.ReadFile(v.fsys, name)
 		if err != nil {
 			return err
 		}
-		if info == nil || info.IsDir() {
-			return nil
-		}
 
-		rel, err := filepath.Rel(v.path, path)
+		// 每个文件单独解析，文件中 define 的模板除了使用原名称，还以 "文件名#名称" 保存，作为该文件的区块
+		file := v.newSet(name, v.left, v.right)
+		file.funcs(funcs)
+		file.funcs(stateFuncs)
+		trees, err := file.parse(string(b))
 		if err != nil {
 			return err
 		}
+		for _, tree := range trees {
+			if tree.Name == name {
+				if parent := extendsOf(tree); parent != "" {
+					parents[name] = parent
+				}
+			} else if err = templates.addParseTree(name+sectionSeparator+tree.Name, tree.Copy()); err != nil {
+				return err
+			}
+			if err = templates.addParseTree(tree.Name, tree); err != nil {
+				return err
+			}
+		}
+	}
 
-		ext := filepath.Ext(path)
+	escape := isHTMLEngine(v.engine)
+	v.templates = templates
+	v.sets = &sync.Pool{
+		New: func() interface{} {
+			return newTemplateSet(templates, parents, escape, v.stateFuncMap)
+		},
+	}
+	return nil
+}
 
-		if ext == v.extension {
-			b, err := ioutil.ReadFile(path)
+// sectionSeparator 分隔文件名和区块名称
+const sectionSeparator = "#"
 
-			if err != nil {
-				return err
+// extendsOf 返回模板顶层的 {{ extends "layout.html" }} 声明的父级布局
+func extendsOf(tree *parse.Tree) string {
+	if tree.Root == nil {
+		return ""
+	}
+	for _, node := range tree.Root.Nodes {
+		action, ok := node.(*parse.ActionNode)
+		if !ok || action.Pipe == nil || len(action.Pipe.Cmds) != 1 {
+			continue
+		}
+		args := action.Pipe.Cmds[0].Args
+		if len(args) != 2 {
+			continue
+		}
+		if ident, ok := args[0].(*parse.IdentifierNode); ok && ident.Ident == "extends" {
+			if str, ok := args[1].(*parse.StringNode); ok {
+				return str.Text
 			}
+		}
+	}
+	return ""
+}
 
-			s := string(b)
-			name := filepath.ToSlash(rel)
+// checkReload 视图文件有新增、删除或者修改时重新加载
+func (v *templateEngine) checkReload() error {
+	files, err := v.scan()
+	if err != nil {
+		return err
+	}
 
-			tmpl := v.templates.New(name)
-			_, err = tmpl.Parse(s)
+	v.mu.RLock()
+	changed := !sameModTimes(files, v.files)
+	loadErr := v.loadErr
+	v.mu.RUnlock()
+	if !changed {
+		return loadErr
+	}
 
-			if err != nil {
-				return err
-			}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if sameModTimes(files, v.files) { // 其它请求已经重新加载
+		return v.loadErr
+	}
+	v.files = files
+	v.loadErr = v.parse(files)
+	return v.loadErr
+}
+
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, t := range a {
+		if bt, ok := b[name]; !ok || !bt.Equal(t) {
+			return false
 		}
+	}
+	return true
+}
 
-		return nil
+// Render 渲染视图
+//
+// 视图先渲染到缓冲区，成功后才写入 w，出错时不会写入任何数据，
+// 返回的错误交给应用的错误处理程序，响应 500
+func (v *templateEngine) Render(w io.Writer, name string, layout string, data interface{}, c *Context) error {
+	return v.buffered(w, func(buf io.Writer) error {
+		return v.render(buf, name, layout, data, c)
 	})
+}
 
-	return
+// RenderLayout 渲染布局，布局中的 content 输出其它视图引擎渲染的 content
+func (v *templateEngine) RenderLayout(w io.Writer, layout string, content string, data interface{}, c *Context) error {
+	return v.buffered(w, func(buf io.Writer) error {
+		return v.renderLayout(buf, layout, content, data, c)
+	})
 }
 
-// Render 渲染视图
-func (v *HTMLEngine) Render(w io.Writer, name string, layout string, data interface{}, c *Context) error {
-	t := v.templates.Lookup(name)
+// buffered 先渲染到缓冲区，成功后写入 w
+func (v *templateEngine) buffered(w io.Writer, render func(io.Writer) error) error {
+	if v.reload {
+		if err := v.checkReload(); err != nil {
+			return v.renderError(err)
+		}
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := render(buf); err != nil {
+		if v.reload {
+			return v.renderError(err)
+		}
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// maxPooledBufferSize 超过此大小的缓冲区不放回池中，避免长期占用内存
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
+	}
+}
+
+func (v *templateEngine) render(w io.Writer, name string, layout string, data interface{}, c *Context) error {
+	set, put, err := v.getSet(name)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	t := set.templates.lookup(name)
 	if t == nil {
 		return fmt.Errorf("template: %s not found", name)
 	}
-	commonFunc := template.FuncMap{
-		"route": func(name string, pairs ...interface{}) (string, error) {
-			return c.URL(name, pairs...), nil
-		},
+
+	// 视图布局，视图中使用 extends 声明的布局优先
+	if parent := set.parents[name]; parent != "" && layout != NoLayout {
+		layout = parent
+	} else {
+		layout = v.getLayout(layout)
+	}
+
+	// 其它视图引擎的布局
+	if layout != "" && path.Ext(layout) != v.extension && set.templates.lookup(layout) == nil {
+		content, err := set.state.executeStack(c, set.templates, []viewTemplate{t}, "", data)
+		if err != nil {
+			return err
+		}
+		return renderLayout(w, v.engine, layout, string(content), data, c)
+	}
+
+	stack, err := set.layoutChain(layout)
+	if err != nil {
+		return err
+	}
+	stack = append(stack, t)
+	set.state.set(c, set.templates, stack, "", data)
+	return stack[0].Execute(w, data)
+}
+
+func (v *templateEngine) renderLayout(w io.Writer, layout string, content string, data interface{}, c *Context) error {
+	set, put, err := v.getSet(layout)
+	if err != nil {
+		return err
+	}
+	defer put()
+
+	stack, err := set.layoutChain(layout)
+	if err != nil {
+		return err
+	}
+	set.state.set(c, set.templates, stack, template.HTML(content), data)
+	return stack[0].Execute(w, data)
+}
+
+// getSet 从池中取出一份克隆的模板，渲染完成后调用 put 放回
+func (v *templateEngine) getSet(name string) (*templateSet, func(), error) {
+	v.mu.RLock()
+	sets := v.sets
+	v.mu.RUnlock()
+
+	if sets == nil {
+		return nil, nil, fmt.Errorf("template: %s not found", name)
+	}
+	set := sets.Get().(*templateSet)
+	put := func() {
+		set.state.reset()
+		sets.Put(set)
 	}
-	t.Funcs(commonFunc)
+	if set.err != nil {
+		put()
+		return nil, nil, set.err
+	}
+	return set, put, nil
+}
+
+// templateSet 克隆的模板，同一时间只被一个请求使用
+type templateSet struct {
+	templates viewTemplates
+	parents   map[string]string // 布局使用 extends 声明的父级布局
+	state     *renderState
+	err       error
+}
+
+// newTemplateSet 克隆模板并绑定请求相关的函数，escape 为 true 时 yield 的默认值需要转义
+func newTemplateSet(prototype viewTemplates, parents map[string]string, escape bool, stateFuncs func(*renderState) map[string]interface{}) *templateSet {
+	set := &templateSet{parents: parents, state: &renderState{escape: escape}}
+	set.templates, set.err = prototype.clone()
+	if set.err == nil {
+		set.templates.funcs(stateFuncs(set.state))
+	}
+	return set
+}
+
+// baseFuncMap 返回与请求无关的视图函数，AddFunc 添加的函数优先于标准视图函数
+func (v *templateEngine) baseFuncMap() map[string]interface{} {
+	funcs := make(map[string]interface{})
+	if v.standardFuncs {
+		for name, fn := range StandardFuncMap() {
+			funcs[name] = fn
+		}
+	}
+	for name, fn := range v.funcMap {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// stateFuncMap 返回绑定到 s 的请求相关的视图函数
+func (v *templateEngine) stateFuncMap(s *renderState) map[string]interface{} {
+	funcs := s.funcMap()
+	if v.standardFuncs {
+		for name, fn := range s.standardFuncMap() {
+			if _, ok := v.funcMap[name]; !ok {
+				funcs[name] = fn
+			}
+		}
+	}
+	return funcs
+}
 
-	if layout = v.getLayout(layout); layout != "" { // 视图布局
-		lt := v.templates.Lookup(layout)
+// layoutChain 返回从最外层开始的布局链
+func (set *templateSet) layoutChain(layout string) ([]viewTemplate, error) {
+	stack := make([]viewTemplate, 0, 2)
+	for l := layout; l != ""; l = set.parents[l] {
+		if len(stack) >= maxLayoutDepth {
+			return nil, fmt.Errorf("layout: %s extends too deep, circular extends?", layout)
+		}
+		lt := set.templates.lookup(l)
 		if lt == nil {
-			return fmt.Errorf("layout: %s not found", layout)
+			return nil, fmt.Errorf("layout: %s not found", l)
+		}
+		stack = append([]viewTemplate{lt}, stack...)
+	}
+	return stack, nil
+}
+
+// maxLayoutDepth 布局链的最大长度
+const maxLayoutDepth = 16
+
+// renderState 一次渲染的状态，模板中请求相关的函数从这里读取当前的请求
+type renderState struct {
+	c         *Context
+	templates viewTemplates
+	stack     []viewTemplate // 从最外层布局到视图
+	depth     int            // 当前执行的模板在 stack 中的位置
+	body      template.HTML  // 其它视图引擎渲染的内容，作为最内层布局的 content
+	data      interface{}
+	escape    bool // yield 的默认值是否需要转义
+}
+
+func (s *renderState) set(c *Context, templates viewTemplates, stack []viewTemplate, body template.HTML, data interface{}) {
+	s.c = c
+	s.templates = templates
+	s.stack = stack
+	s.body = body
+	s.data = data
+}
+
+func (s *renderState) reset() {
+	s.set(nil, nil, nil, "", nil)
+	s.depth = 0
+}
+
+// executeStack 执行 stack 并返回结果
+func (s *renderState) executeStack(c *Context, templates viewTemplates, stack []viewTemplate, body template.HTML, data interface{}) (template.HTML, error) {
+	s.set(c, templates, stack, body, data)
+	return s.execute(stack[0], data)
+}
+
+// content 渲染布局链中的下一层
+func (s *renderState) content() (template.HTML, error) {
+	next := s.depth + 1
+	if next >= len(s.stack) {
+		return s.body, nil
+	}
+	depth := s.depth
+	s.depth = next
+	defer func() {
+		s.depth = depth
+	}()
+	return s.execute(s.stack[next], s.data)
+}
+
+// section 从视图开始向外层布局查找区块
+func (s *renderState) section(name string) viewTemplate {
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if t := s.templates.lookup(s.stack[i].Name() + sectionSeparator + name); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// yield 渲染区块，区块不存在时返回默认值
+func (s *renderState) yield(name string, defaultValue ...interface{}) (template.HTML, error) {
+	if s.templates != nil {
+		if t := s.section(name); t != nil {
+			return s.execute(t, s.data)
 		}
-		lt.Funcs(commonFunc)
-		lt.Funcs(template.FuncMap{
-			"content": func() (template.HTML, error) {
-				buf := new(bytes.Buffer)
-				err := t.Execute(buf, data) // 当前视图
-				return template.HTML(buf.String()), err
-			},
-		})
-		return lt.Execute(w, data)
 	}
-	return t.Execute(w, data)
+	if len(defaultValue) == 0 {
+		return "", nil
+	}
+	if h, ok := defaultValue[0].(template.HTML); ok {
+		return h, nil
+	}
+	if !s.escape {
+		return template.HTML(fmt.Sprint(defaultValue[0])), nil
+	}
+	return template.HTML(template.HTMLEscapeString(fmt.Sprint(defaultValue[0]))), nil
+}
+
+// partial 使用指定的数据渲染视图
+func (s *renderState) partial(name string, data ...interface{}) (template.HTML, error) {
+	if s.templates == nil {
+		return "", nil
+	}
+	t := s.templates.lookup(name)
+	if t == nil {
+		return "", fmt.Errorf("partial: %s not found", name)
+	}
+	var d interface{}
+	if len(data) > 0 {
+		d = data[0]
+	}
+	return s.execute(t, d)
 }
 
-func (v *HTMLEngine) getLayout(layout string) string {
+func (s *renderState) execute(t viewTemplate, data interface{}) (template.HTML, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	err := t.Execute(buf, data)
+	return template.HTML(buf.String()), err
+}
+
+// funcMap 返回请求相关的模板函数，没有请求时返回零值
+func (s *renderState) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"content": s.content,
+		"yield":   s.yield,
+		"hasSection": func(name string) bool {
+			return s.templates != nil && s.section(name) != nil
+		},
+		"partial": s.partial,
+		"extends": func(layout string) string {
+			return ""
+		},
+		"route": func(name string, pairs ...interface{}) (string, error) {
+			if s.c == nil {
+				return "", nil
+			}
+			return s.c.URL(name, pairs...), nil
+		},
+		"flashes": func(kind ...string) []FlashMessage {
+			if s.c == nil {
+				return nil
+			}
+			return s.c.Flashes(kind...)
+		},
+		"old": func(field string, defaultValue ...string) string {
+			if s.c == nil {
+				return ""
+			}
+			return s.c.OldInput(field, defaultValue...)
+		},
+		"csrfToken": func() string {
+			if s.c == nil {
+				return ""
+			}
+			return s.c.CSRFToken()
+		},
+		"csrfField": func() template.HTML {
+			if s.c == nil {
+				return ""
+			}
+			return s.c.CSRFField()
+		},
+		"cspNonce": func() string {
+			if s.c == nil {
+				return ""
+			}
+			return s.c.CSPNonce()
+		},
+		"t": func(key string, args ...interface{}) string {
+			if s.c == nil {
+				return key
+			}
+			return s.c.T(key, args...)
+		},
+	}
+}
+
+func (v *templateEngine) getLayout(layout string) string {
 	if layout == NoLayout {
 		return ""
 	}
@@ -157,3 +701,87 @@ func (v *HTMLEngine) getLayout(layout string) string {
 	}
 	return layout
 }
+
+// HTMLEngine HTML 引擎，使用 html/template，输出会根据上下文自动转义
+type HTMLEngine struct {
+	templateEngine
+}
+
+var _ LayoutEngine = &HTMLEngine{}
+
+// HTML 创建 HTML 对象
+func HTML(path string, extension string) *HTMLEngine {
+	v := HTMLFS(os.DirFS(path), ".", extension)
+	v.path = path
+	return v
+}
+
+// HTMLFS 使用 fs.FS 中 root 目录下的视图文件创建 HTML 对象，例如 embed.FS
+//
+//	//go:embed views
+//	var views embed.FS
+//
+//	app.RegisterView(potgo.HTMLFS(views, "views", ".html"))
+func HTMLFS(fsys fs.FS, root string, extension string) *HTMLEngine {
+	v := new(HTMLEngine)
+	v.init(v, newHTMLTemplates, fsys, root, extension)
+	return v
+}
+
+// Func 设置视图函数
+func (v *HTMLEngine) Func(funcMap template.FuncMap) {
+	for name, value := range funcMap {
+		v.funcMap[name] = value
+	}
+}
+
+// ContentType 返回渲染结果的内容类型
+func (v *HTMLEngine) ContentType() string {
+	return defaultContentType
+}
+
+// htmlTemplates 使用 html/template 的模板集合
+type htmlTemplates struct {
+	t *template.Template
+}
+
+func newHTMLTemplates(name, left, right string) viewTemplates {
+	return htmlTemplates{template.New(name).Delims(left, right)}
+}
+
+func (ts htmlTemplates) lookup(name string) viewTemplate {
+	if t := ts.t.Lookup(name); t != nil {
+		return t
+	}
+	return nil
+}
+
+func (ts htmlTemplates) parse(text string) ([]*parse.Tree, error) {
+	if _, err := ts.t.Parse(text); err != nil {
+		return nil, err
+	}
+	var trees []*parse.Tree
+	for _, t := range ts.t.Templates() {
+		if t.Tree != nil {
+			trees = append(trees, t.Tree)
+		}
+	}
+	return trees, nil
+}
+
+func (ts htmlTemplates) addParseTree(name string, tree *parse.Tree) error {
+	_, err := ts.t.AddParseTree(name, tree)
+	return err
+}
+
+func (ts htmlTemplates) funcs(funcMap map[string]interface{}) {
+	ts.t.Funcs(funcMap)
+}
+
+func (ts htmlTemplates) clone() (viewTemplates, error) {
+	t, err := ts.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return htmlTemplates{t}, nil
+}
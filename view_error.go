@@ -0,0 +1,136 @@
+package potgo
+
+import (
+	"bufio"
+	"bytes"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// templateErrorRegexp 匹配模板解析和执行错误中的视图名称、行号和列号
+//
+//	template: users.html:3: function "foo" not defined
+//	template: users.html:1:11: executing "users.html" at <.foo>: ...
+//	html/template:users.html:1: ...
+var templateErrorRegexp = regexp.MustCompile(`(?s)^(?:html/)?template: ?([^:]+):(\d+)(?::(\d+))?: (.*)$`)
+
+// TemplateError 包含出错位置的视图错误
+type TemplateError struct {
+	Name    string // 视图名称
+	File    string // 视图文件路径
+	Line    int    // 行号，从 1 开始，0 表示未知
+	Column  int    // 列号，0 表示未知
+	Message string // 去掉位置信息的错误信息
+	Err     error  // 原始错误
+	fsys    fs.FS
+	debug   bool // 是否由默认错误处理程序显示为调试页面
+}
+
+// TemplateSnippetLine 出错位置附近的一行代码
+type TemplateSnippetLine struct {
+	Number  int
+	Text    string
+	Current bool // 是否为出错的行
+}
+
+// Error 返回错误信息
+func (e *TemplateError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet 返回出错位置前后 around 行的代码
+func (e *TemplateError) Snippet(around int) []TemplateSnippetLine {
+	if e.fsys == nil || e.Name == "" || e.Line <= 0 {
+		return nil
+	}
+	f, err := e.fsys.Open(e.Name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []TemplateSnippetLine
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan() && n <= e.Line+around; n++ {
+		if n >= e.Line-around {
+			lines = append(lines, TemplateSnippetLine{Number: n, Text: scanner.Text(), Current: n == e.Line})
+		}
+	}
+	return lines
+}
+
+// newTemplateError 从模板错误中解析视图名称和位置，fsys 为视图文件所在的 fs.FS，root 为显示的视图目录
+func newTemplateError(err error, fsys fs.FS, root string) *TemplateError {
+	if te, ok := err.(*TemplateError); ok {
+		return te
+	}
+	te := &TemplateError{Message: err.Error(), Err: err, fsys: fsys}
+	if m := templateErrorRegexp.FindStringSubmatch(err.Error()); m != nil {
+		te.Name = m[1]
+		te.File = filepath.Join(root, filepath.FromSlash(m[1]))
+		te.Line, _ = strconv.Atoi(m[2])
+		te.Column, _ = strconv.Atoi(m[3])
+		te.Message = m[4]
+	}
+	return te
+}
+
+// renderError 返回包含出错位置的 *TemplateError，由默认错误处理程序显示为调试页面
+func (v *templateEngine) renderError(err error) error {
+	te := newTemplateError(err, v.fsys, v.path)
+	te.debug = true
+	return te
+}
+
+// writePage 写入视图错误的调试页面，响应 500
+func (e *TemplateError) writePage(w http.ResponseWriter) error {
+	buf := new(bytes.Buffer)
+	err := templateErrorPage.Execute(buf, map[string]interface{}{
+		"Error":   e,
+		"Snippet": e.Snippet(5),
+	})
+	if err != nil {
+		return err
+	}
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+var templateErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Template Error</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #333; }
+h1 { color: #c00; font-size: 1.4em; }
+.message { background: #fee; border-left: 4px solid #c00; padding: .8em 1em; white-space: pre-wrap; }
+.snippet { background: #f6f6f6; font-family: monospace; padding: .5em 0; }
+.line { white-space: pre; padding: 0 1em; }
+.line span { display: inline-block; width: 3em; color: #999; }
+.current { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Template Error</h1>
+{{ with .Error }}{{ if .Name }}<p><strong>{{ .File }}</strong>{{ if .Line }} line {{ .Line }}{{ if .Column }}, column {{ .Column }}{{ end }}{{ end }}</p>{{ end }}
+<div class="message">{{ .Message }}</div>{{ end }}
+{{ with .Snippet }}<div class="snippet">{{ range . }}
+<div class="line{{ if .Current }} current{{ end }}"><span>{{ .Number }}</span>{{ .Text }}</div>{{ end }}
+</div>{{ end }}
+</body>
+</html>
+`))
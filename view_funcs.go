@@ -0,0 +1,303 @@
+package potgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StandardFuncs 设置是否添加标准视图函数，需要在 Load 之前调用，AddFunc 添加的同名函数优先，
+// HTMLEngine 和 TextEngine 都可以使用
+//
+// 标准视图函数包括 StandardFuncMap 返回的函数，以及与请求相关的 currentRoute、query 和 pagination
+func (v *templateEngine) StandardFuncs(enabled bool) {
+	v.standardFuncs = enabled
+}
+
+// StandardFuncMap 返回与请求无关的标准视图函数，也可以添加到其它视图引擎
+//
+//	字符串：upper、lower、title、trim、trimPrefix、trimSuffix、replace、contains、hasPrefix、hasSuffix、split、join、repeat、truncate
+//	日期：now、date，date 支持 Go 和 strftime 格式，例如 {{ date "2006-01-02" .t }}、{{ date "%Y-%m-%d" .t }}
+//	数字：number、bytes，例如 {{ number 2 1234.5 }} 输出 1,234.50，{{ bytes 1536 }} 输出 1.5 KB
+//	数据：dict、list、default、json
+//	安全类型：safeHTML、safeURL、safeJS、safeCSS、safeAttr
+func StandardFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"truncate":   truncate,
+		"now":        time.Now,
+		"date":       formatDate,
+		"number":     formatNumber,
+		"bytes":      formatBytes,
+		"dict":       dict,
+		"list":       func(values ...interface{}) []interface{} { return values },
+		"default":    defaultValue,
+		"json":       toJSON,
+		"safeHTML":   func(s string) template.HTML { return template.HTML(s) },
+		"safeURL":    func(s string) template.URL { return template.URL(s) },
+		"safeJS":     func(s string) template.JS { return template.JS(s) },
+		"safeCSS":    func(s string) template.CSS { return template.CSS(s) },
+		"safeAttr":   func(s string) template.HTMLAttr { return template.HTMLAttr(s) },
+	}
+}
+
+// standardFuncMap 返回与请求相关的标准视图函数，没有请求时返回零值
+func (s *renderState) standardFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"currentRoute": func() string {
+			if s.c == nil || s.c.Route() == nil {
+				return ""
+			}
+			return s.c.Route().GetName()
+		},
+		"query": func(key string) string {
+			if s.c == nil || s.c.Request == nil {
+				return ""
+			}
+			return s.c.Query(key)
+		},
+		"pagination": func(current, total int) *Pagination {
+			var u *url.URL
+			if s.c != nil && s.c.Request != nil {
+				u = s.c.Request.URL
+			}
+			return NewPagination(current, total, u)
+		},
+	}
+}
+
+// title 将每个单词的首字母转换为大写
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) || unicode.IsPunct(prev) && prev != '\'' {
+			return unicode.ToTitle(r)
+		}
+		return r
+	}, s)
+}
+
+// join 使用 sep 连接切片中的元素
+func join(sep string, elems interface{}) (string, error) {
+	if ss, ok := elems.([]string); ok {
+		return strings.Join(ss, sep), nil
+	}
+	rv := reflect.ValueOf(elems)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("join: %T is not a slice", elems)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// truncate 截取前 length 个字符，超过时以 … 结尾
+func truncate(length int, s string) string {
+	if length <= 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:length]) + "…"
+}
+
+// dict 使用 "键, 值" 参数创建 map，用于向 partial 传递多个参数
+//
+//	{{ partial "user.html" (dict "user" .user "editable" true) }}
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: odd number of arguments")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// defaultValue value 为空值时返回 def
+//
+//	{{ .name | default "Guest" }}
+func defaultValue(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || value[0] == nil {
+		return def
+	}
+	rv := reflect.ValueOf(value[0])
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if rv.Len() == 0 {
+			return def
+		}
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return def
+		}
+	default:
+		if rv.IsZero() {
+			return def
+		}
+	}
+	return value[0]
+}
+
+// toJSON 把数据编码为 JSON，可以直接在 <script> 中使用
+func toJSON(v interface{}) (template.JS, error) {
+	b, err := json.Marshal(v)
+	return template.JS(b), err
+}
+
+// toTime 转换 time.Time、*time.Time 和 Unix 时间戳
+func toTime(t interface{}) (time.Time, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, nil
+		}
+		return *v, nil
+	case int:
+		return time.Unix(int64(v), 0), nil
+	case int64:
+		return time.Unix(v, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("date: unsupported time %T", t)
+}
+
+// formatDate 格式化时间，layout 中包含 % 时使用 strftime 格式，否则使用 Go 的格式
+func formatDate(layout string, t interface{}) (string, error) {
+	tm, err := toTime(t)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(layout, "%") {
+		return tm.Format(layout), nil
+	}
+	return strftime(layout, tm), nil
+}
+
+// strftimeLayouts strftime 格式对应的 Go 格式
+var strftimeLayouts = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2",
+	'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+	'b': "Jan", 'h': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+	'Z': "MST", 'z': "-0700",
+	'F': "2006-01-02", 'T': "15:04:05", 'D': "01/02/06", 'R': "15:04",
+}
+
+// strftime 使用 strftime 格式格式化时间，不支持的格式原样输出
+func strftime(layout string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' || i == len(layout)-1 {
+			b.WriteByte(layout[i])
+			continue
+		}
+		i++
+		switch ch := layout[i]; ch {
+		case '%':
+			b.WriteByte('%')
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		default:
+			if l, ok := strftimeLayouts[ch]; ok {
+				b.WriteString(t.Format(l))
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(ch)
+			}
+		}
+	}
+	return b.String()
+}
+
+// toFloat 转换数字
+func toFloat(n interface{}) (float64, error) {
+	rv := reflect.ValueOf(n)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return strconv.ParseFloat(rv.String(), 64)
+	}
+	return 0, fmt.Errorf("%T is not a number", n)
+}
+
+// formatNumber 使用千位分隔符格式化数字，decimals 为小数位数
+//
+//	{{ number 0 1234567 }} 输出 1,234,567
+func formatNumber(decimals int, n interface{}) (string, error) {
+	f, err := toFloat(n)
+	if err != nil {
+		return "", fmt.Errorf("number: %w", err)
+	}
+	s := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	integer, fraction := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		integer, fraction = s[:dot], s[dot:]
+	}
+
+	var b strings.Builder
+	if f < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, ch := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(ch)
+	}
+	b.WriteString(fraction)
+	return b.String(), nil
+}
+
+// formatBytes 格式化字节数，例如 1536 输出 1.5 KB
+func formatBytes(n interface{}) (string, error) {
+	f, err := toFloat(n)
+	if err != nil {
+		return "", fmt.Errorf("bytes: %w", err)
+	}
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+	i := 0
+	for math.Abs(f) >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", int64(f)), nil
+	}
+	return strings.TrimSuffix(strconv.FormatFloat(f, 'f', 1, 64), ".0") + " " + units[i], nil
+}
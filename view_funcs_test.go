@@ -0,0 +1,85 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestStandardFuncMap(t *testing.T) {
+	tm := time.Date(2020, 9, 5, 14, 3, 7, 0, time.UTC)
+	tests := []struct {
+		tpl  string
+		want string
+	}{
+		{`{{ upper "abc" }} {{ lower "ABC" }} {{ title "hello wORLD-foo" }}`, "ABC abc Hello WORLD-Foo"},
+		{`{{ trim "  a " }}|{{ "a.go" | trimSuffix ".go" }}|{{ "/a" | trimPrefix "/" }}`, "a|a|a"},
+		{`{{ "a-b-c" | replace "-" "_" }} {{ contains "b" "abc" }} {{ hasPrefix "a" "abc" }} {{ hasSuffix "a" "abc" }}`, "a_b_c true true false"},
+		{`{{ split "," "a,b" | join "|" }} {{ join ", " .nums }} {{ repeat 3 "ab" }}`, "a|b 1, 2 ababab"},
+		{`{{ truncate 5 "你好，世界！再见" }} {{ truncate 10 "short" }}`, "你好，世界… short"},
+		{`{{ date "2006-01-02 15:04" .t }} {{ date "%Y/%m/%d %H:%M:%S %a %b %e %j %%" .t }}`, "2020-09-05 14:03 2020/09/05 14:03:07 Sat Sep  5 249 %"},
+		{`{{ date "%F %T %q" .t }} {{ date "%Y" 0 }}`, "2020-09-05 14:03:07 %q 1970"},
+		{`{{ number 0 1234567 }} {{ number 2 -1234.567 }} {{ number 1 "999.95" }} {{ number 0 -0.4 }}`, "1,234,567 -1,234.57 1,000.0 0"},
+		{`{{ bytes 512 }} {{ bytes 1536 }} {{ bytes 1048576 }} {{ bytes 1099511627776 }}`, "512 B 1.5 KB 1 MB 1 TB"},
+		{`{{ with dict "a" 1 "b" "x" }}{{ .a }}{{ .b }}{{ end }} {{ range list 1 2 }}{{ . }}{{ end }}`, "1x 12"},
+		{`{{ .empty | default "guest" }} {{ .name | default "guest" }} {{ 0 | default 5 }} {{ .missing | default "none" }}`, "guest tom 5 none"},
+		{`<script>var data = {{ json .nums }};</script>`, "<script>var data = [1,2];</script>"},
+		{`{{ safeHTML "<b>x</b>" }} <a href="{{ safeURL "javascript:void(0)" }}" {{ safeAttr "data-x=\"1\"" }}>`, `<b>x</b> <a href="javascript:void%280%29" data-x="1">`},
+	}
+
+	for _, tt := range tests {
+		view := HTMLFS(fstest.MapFS{"t.html": {Data: []byte(tt.tpl)}}, ".", ".html")
+		view.StandardFuncs(true)
+		assert.Nil(t, view.Load(), tt.tpl)
+
+		res := httptest.NewRecorder()
+		err := view.Render(res, "t.html", "", Map{"t": tm, "nums": []int{1, 2}, "empty": "", "name": "tom"}, &Context{})
+		assert.Nil(t, err, tt.tpl)
+		assert.Equal(t, tt.want, res.Body.String(), tt.tpl)
+	}
+
+	_, err := dict("a")
+	assert.NotNil(t, err)
+	_, err = dict(1, 2)
+	assert.NotNil(t, err)
+	_, err = formatNumber(0, "x")
+	assert.NotNil(t, err)
+	_, err = formatDate("2006", "2020")
+	assert.NotNil(t, err)
+	_, err = join(",", 1)
+	assert.NotNil(t, err)
+}
+
+func TestHTMLEngine_StandardFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users.html": {Data: []byte(`{{ currentRoute }} {{ query "q" }} {{ upper "a" }} {{ (pagination 2 3).HTML }}`)},
+		"plain.html": {Data: []byte(`{{ upper "a" }}`)},
+	}
+
+	// 没有开启时不能使用标准视图函数
+	assert.NotNil(t, HTMLFS(fsys, ".", ".html").Load())
+
+	app := New()
+	view := HTMLFS(fsys, ".", ".html")
+	view.StandardFuncs(true)
+	view.AddFunc("upper", func(s string) string { return "custom" })
+	_ = app.RegisterView(view)
+	app.GET("/users", func(c *Context) error {
+		return c.View("users.html")
+	}).Name("users.index")
+
+	req, _ := http.NewRequest("GET", "/users?q=go&page=2", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, `users.index go custom <nav class="pagination"><a href="/users?page=1&amp;q=go" rel="prev">&laquo;</a>`+
+		`<a href="/users?page=1&amp;q=go">1</a><span class="current">2</span><a href="/users?page=3&amp;q=go">3</a>`+
+		`<a href="/users?page=3&amp;q=go" rel="next">&raquo;</a></nav>`, res.Body.String())
+
+	// 没有请求时请求相关的函数返回零值
+	s, err := app.RenderToString("users.html", "", nil)
+	assert.Nil(t, err)
+	assert.Contains(t, s, " custom ")
+}
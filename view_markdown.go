@@ -0,0 +1,252 @@
+package potgo
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MarkdownEngine Markdown 引擎，视图文件转换为 HTML 后放入其它视图引擎的布局中
+//
+//	app.RegisterView(potgo.HTML("./views", ".html"))
+//	md := potgo.Markdown("./views", ".md")
+//	md.Layout("layout.html")
+//	app.RegisterView(md)
+type MarkdownEngine struct {
+	mu        sync.RWMutex
+	pages     map[string]string
+	loadErr   error
+	fsys      fs.FS
+	extension string
+	layout    string
+	converter func([]byte) []byte
+}
+
+var _ ViewEngine = &MarkdownEngine{}
+
+// Markdown 创建 Markdown 引擎
+func Markdown(path string, extension string) *MarkdownEngine {
+	return MarkdownFS(os.DirFS(path), ".", extension)
+}
+
+// MarkdownFS 使用 fs.FS 中 root 目录下的视图文件创建 Markdown 引擎
+func MarkdownFS(fsys fs.FS, root string, extension string) *MarkdownEngine {
+	v := &MarkdownEngine{
+		fsys:      fsys,
+		extension: extension,
+		converter: MarkdownToHTML,
+	}
+	if root != "" && root != "." {
+		v.fsys, v.loadErr = fs.Sub(fsys, root)
+	}
+	return v
+}
+
+// Layout 设置视图布局文件，布局由布局扩展名对应的视图引擎渲染，布局中使用 content 输出转换后的 HTML
+func (v *MarkdownEngine) Layout(layout string) {
+	v.layout = layout
+}
+
+// Converter 设置 Markdown 转换为 HTML 的函数，默认为 MarkdownToHTML
+func (v *MarkdownEngine) Converter(converter func(source []byte) []byte) {
+	v.converter = converter
+}
+
+// Extension 返回视图文件的扩展名
+func (v *MarkdownEngine) Extension() string {
+	return v.extension
+}
+
+// ContentType 返回渲染结果的内容类型
+func (v *MarkdownEngine) ContentType() string {
+	return defaultContentType
+}
+
+// Load 加载并转换视图文件下的所有视图文件
+func (v *MarkdownEngine) Load() error {
+	if v.fsys == nil { // fs.Sub 失败
+		return v.loadErr
+	}
+	files, err := scanViews(v.fsys, v.extension)
+	if err != nil {
+		return err
+	}
+
+	pages := make(map[string]string, len(files))
+	for name := range files {
+		b, err := fs.ReadFile(v.fsys, name)
+		if err != nil {
+			return err
+		}
+		pages[name] = string(v.converter(b))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pages = pages
+	return nil
+}
+
+// Render 渲染视图，data 作为布局的数据
+func (v *MarkdownEngine) Render(w io.Writer, name string, layout string, data interface{}, c *Context) error {
+	v.mu.RLock()
+	page, ok := v.pages[name]
+	v.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("markdown: %s not found", name)
+	}
+
+	if layout == NoLayout {
+		layout = ""
+	} else if layout == "" {
+		layout = v.layout
+	}
+	if layout == "" {
+		_, err := io.WriteString(w, page)
+		return err
+	}
+	return renderLayout(w, v, layout, page, data, c)
+}
+
+var (
+	markdownHeading   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownRule      = regexp.MustCompile(`^(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	markdownUnordered = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	markdownOrdered   = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	markdownImage     = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	markdownLink      = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownStrong    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownEmphasis  = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+	markdownCodeSpan  = regexp.MustCompile("`([^`]+)`")
+	markdownScheme    = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// MarkdownToHTML 把 Markdown 转换为 HTML
+//
+// 支持标题、段落、引用、列表、代码块、分割线以及行内的强调、代码、链接和图片，
+// Markdown 中的 HTML 会被转义，需要完整的 Markdown 支持时使用 MarkdownEngine.Converter 替换
+func MarkdownToHTML(source []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(source), "\r\n", "\n"), "\n")
+	var b strings.Builder
+	markdownBlocks(&b, lines)
+	return []byte(b.String())
+}
+
+// markdownBlocks 转换块级元素
+func markdownBlocks(b *strings.Builder, lines []string) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + markdownInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			if lang != "" {
+				b.WriteString(`<pre><code class="language-` + html.EscapeString(lang) + `">`)
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			for _, l := range code {
+				b.WriteString(html.EscapeString(l) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+		case markdownHeading.MatchString(trimmed):
+			flush()
+			m := markdownHeading.FindStringSubmatch(trimmed)
+			level := len(m[1])
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, markdownInline(m[2]), level)
+		case markdownRule.MatchString(trimmed):
+			flush()
+			b.WriteString("<hr>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quote []string
+			for ; i < len(lines); i++ {
+				l := strings.TrimSpace(lines[i])
+				if !strings.HasPrefix(l, ">") {
+					i--
+					break
+				}
+				quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(l, ">"), " "))
+			}
+			b.WriteString("<blockquote>\n")
+			markdownBlocks(b, quote)
+			b.WriteString("</blockquote>\n")
+		case markdownUnordered.MatchString(trimmed), markdownOrdered.MatchString(trimmed):
+			flush()
+			tag, item := "ul", markdownUnordered
+			if !markdownUnordered.MatchString(trimmed) {
+				tag, item = "ol", markdownOrdered
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines); i++ {
+				m := item.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				if m == nil {
+					i--
+					break
+				}
+				b.WriteString("<li>" + markdownInline(m[1]) + "</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+}
+
+// markdownInline 转换行内元素，代码中的内容不再转换
+func markdownInline(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range markdownCodeSpan.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(markdownSpan(text[last:m[0]]))
+		b.WriteString("<code>" + html.EscapeString(text[m[2]:m[3]]) + "</code>")
+		last = m[1]
+	}
+	b.WriteString(markdownSpan(text[last:]))
+	return b.String()
+}
+
+func markdownSpan(text string) string {
+	text = html.EscapeString(text)
+	text = markdownImage.ReplaceAllStringFunc(text, func(s string) string {
+		m := markdownImage.FindStringSubmatch(s)
+		return `<img src="` + markdownURL(m[2]) + `" alt="` + m[1] + `">`
+	})
+	text = markdownLink.ReplaceAllStringFunc(text, func(s string) string {
+		m := markdownLink.FindStringSubmatch(s)
+		return `<a href="` + markdownURL(m[2]) + `">` + m[1] + `</a>`
+	})
+	text = markdownStrong.ReplaceAllString(text, "<strong>$1$2</strong>")
+	return markdownEmphasis.ReplaceAllString(text, "<em>$1$2</em>")
+}
+
+// markdownURL 只允许 http、https、mailto 和相对地址
+func markdownURL(u string) string {
+	scheme := strings.ToLower(markdownScheme.FindString(html.UnescapeString(u)))
+	if scheme == "" || scheme == "http:" || scheme == "https:" || scheme == "mailto:" {
+		return u
+	}
+	return "#"
+}
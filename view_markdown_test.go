@@ -0,0 +1,79 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	source := "# Title #\n\nHello **bold** and *em* with `<code>`\nnext line\n\n" +
+		"- [link](https://example.com/?a=1&b=2)\n- ![img](/a.png)\n\n1. one\n2. two\n\n" +
+		"> quote\n\n```go\nfmt.Println(\"<b>\")\n```\n\n---\n\n<script>x</script> [bad](javascript:alert(1)) snake_case_name"
+
+	expected := "<h1>Title</h1>\n" +
+		"<p>Hello <strong>bold</strong> and <em>em</em> with <code>&lt;code&gt;</code>\nnext line</p>\n" +
+		"<ul>\n<li><a href=\"https://example.com/?a=1&amp;b=2\">link</a></li>\n<li><img src=\"/a.png\" alt=\"img\"></li>\n</ul>\n" +
+		"<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n" +
+		"<blockquote>\n<p>quote</p>\n</blockquote>\n" +
+		"<pre><code class=\"language-go\">fmt.Println(&#34;&lt;b&gt;&#34;)\n</code></pre>\n" +
+		"<hr>\n" +
+		"<p>&lt;script&gt;x&lt;/script&gt; <a href=\"#\">bad</a>) snake_case_name</p>\n"
+	assert.Equal(t, expected, string(MarkdownToHTML([]byte(source))))
+}
+
+func TestMarkdownEngine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html":   {Data: []byte(`<title>{{ .title }}</title><main>{{ content }}</main>`)},
+		"index.html":    {Data: []byte(`<h1>{{ .title }}</h1>`)},
+		"docs/intro.md": {Data: []byte("# Intro")},
+		"mail/hi.txt":   {Data: []byte("Hi {{ .title }}")},
+	}
+	app := New()
+	html := HTMLFS(fsys, ".", ".html")
+	assert.Nil(t, app.RegisterView(html))
+	md := MarkdownFS(fsys, ".", ".md")
+	md.Layout("layout.html")
+	assert.Nil(t, app.RegisterView(md))
+	assert.Nil(t, app.RegisterView(TextFS(fsys, ".", ".txt")))
+
+	app.GET("/docs", func(c *Context) error {
+		return c.View("docs/intro.md", Map{"title": "<Docs>"})
+	})
+	app.GET("/index", func(c *Context) error {
+		c.Layout("layout.html")
+		return c.View("index.html", Map{"title": "Home"})
+	})
+	app.GET("/raw", func(c *Context) error {
+		c.NoLayout()
+		return c.View("docs/intro.md")
+	})
+
+	for path, body := range map[string]string{
+		"/docs":  "<title>&lt;Docs&gt;</title><main><h1>Intro</h1>\n</main>",
+		"/index": "<title>Home</title><main><h1>Home</h1></main>",
+		"/raw":   "<h1>Intro</h1>\n",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, body, res.Body.String(), path)
+	}
+
+	// 纯文本视图使用 HTML 布局时内容被转义
+	s, err := app.RenderToString("mail/hi.txt", "layout.html", Map{"title": "<script>"})
+	assert.Nil(t, err)
+	assert.Equal(t, "<title>&lt;script&gt;</title><main>Hi &lt;script&gt;</main>", s)
+
+	md.Converter(func(source []byte) []byte { return []byte("converted") })
+	assert.Nil(t, md.Load())
+	res := httptest.NewRecorder()
+	assert.Nil(t, md.Render(res, "docs/intro.md", NoLayout, nil, nil))
+	assert.Equal(t, "converted", res.Body.String())
+
+	// 没有注册布局对应的视图引擎
+	assert.EqualError(t, md.Render(httptest.NewRecorder(), "docs/intro.md", "", nil, &Context{}), "layout: no view engine for layout.html")
+	assert.NotNil(t, md.Render(httptest.NewRecorder(), "missing.md", "", nil, nil))
+}
@@ -1,12 +1,18 @@
 package potgo
 
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -14,7 +20,7 @@ func TestHTML(t *testing.T) {
 	view := HTML("./testdata/views_1", ".html")
 	err := view.Load()
 	assert.Nil(t, err)
-	tmpl := view.templates.Lookup("hello.html")
+	tmpl := view.templates.lookup("hello.html")
 	assert.NotNil(t, tmpl)
 	assert.Equal(t, "hello.html", tmpl.Name())
 }
@@ -86,3 +92,282 @@ func TestHTMLEngine_Func(t *testing.T) {
 		assert.Equal(t, "Date: 2020-09-15", string(resp))
 	}
 }
+
+func TestHTMLEngine_ConcurrentRender(t *testing.T) {
+	app := New()
+	view := HTML("./testdata/views_4", ".html")
+	// 让出处理器，使并发的渲染交错执行
+	view.AddFunc("pause", func() string {
+		runtime.Gosched()
+		return ""
+	})
+	app.RegisterView(view)
+
+	app.GET("/users/{id}", func(c *Context) error {
+		return c.View("user_link.html", Map{"id": c.Param("id")})
+	}).Name("user")
+	app.GET("/other/{id}", func(c *Context) error {
+		return c.View("users.html", Map{"id": c.Param("id")})
+	})
+	app.GET("/plain/{id}", func(c *Context) error {
+		c.Layout(NoLayout)
+		return c.View("hello.html", Map{"name": c.Param("id")})
+	})
+	view.Layout("layout.html")
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 300)
+	for i := 0; i < 100; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/users/%d", i), nil)
+			app.ServeHTTP(res, req)
+			if expected := fmt.Sprintf(`<main id="Content"><a href="/users/%d">%d</a></main>`, i, i); res.Body.String() != expected {
+				errs <- res.Body.String()
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/other/%d", i), nil)
+			app.ServeHTTP(res, req)
+			if expected := fmt.Sprintf(`<main id="Content"><h1>user: %d</h1></main>`, i); res.Body.String() != expected {
+				errs <- res.Body.String()
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			res := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/plain/%d", i), nil)
+			app.ServeHTTP(res, req)
+			if expected := fmt.Sprintf(`<h1>Hello, %d</h1>`, i); res.Body.String() != expected {
+				errs <- res.Body.String()
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for s := range errs {
+		t.Errorf("unexpected output: %s", s)
+	}
+}
+
+func TestHTMLEngine_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "potgo-views")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "page.html")
+	write := func(content string, mtime time.Time) {
+		assert.Nil(t, ioutil.WriteFile(page, []byte(content), 0644))
+		assert.Nil(t, os.Chtimes(page, mtime, mtime))
+	}
+	mtime := time.Now().Add(-time.Hour)
+	write("<p>{{ .name }}</p>", mtime)
+
+	view := HTML(dir, ".html")
+	view.Reload(true)
+	app := New()
+	assert.Nil(t, app.RegisterView(view))
+	app.GET("/", func(c *Context) error {
+		return c.View("page.html", Map{"name": "foo"})
+	})
+
+	// 调试页面由默认错误处理程序显示
+	render := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/", nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		return res
+	}
+	assert.Equal(t, "<p>foo</p>", render().Body.String())
+
+	write("<h1>{{ .name }}</h1>", mtime.Add(time.Second))
+	assert.Equal(t, "<h1>foo</h1>", render().Body.String())
+
+	// 解析错误显示调试页面
+	write("<h1>\n{{ .name }}\n{{ if }}\n</h1>", mtime.Add(2*time.Second))
+	res := render()
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), page)
+	assert.Contains(t, res.Body.String(), "line 3")
+	assert.Contains(t, res.Body.String(), "missing value for if")
+	assert.Contains(t, res.Body.String(), `<div class="line current"><span>3</span>{{ if }}</div>`)
+
+	// 没有修改时继续显示错误
+	assert.Equal(t, http.StatusInternalServerError, render().Code)
+
+	write("<h1>{{ .name.foo }}</h1>", mtime.Add(3*time.Second))
+	res = render()
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Contains(t, res.Body.String(), "line 1, column")
+
+	// 引擎本身不写入调试页面，而是返回 *TemplateError
+	res = httptest.NewRecorder()
+	err = view.Render(res, "page.html", "", Map{"name": "foo"}, &Context{})
+	_, ok := err.(*TemplateError)
+	assert.True(t, ok)
+	assert.Equal(t, 0, res.Body.Len())
+
+	// 设置了错误处理程序时交给该处理程序
+	var handled string
+	app.Error(func(c *Context, error string, code int) {
+		handled = error
+		_, _ = c.WriteWithStatus(code, []byte("custom"))
+	})
+	res = render()
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, "custom", res.Body.String())
+	assert.Contains(t, handled, "page.html")
+
+	// 新增的视图
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "new.html"), []byte("new"), 0644))
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "new.html", "", nil, &Context{}))
+	assert.Equal(t, "new", res.Body.String())
+}
+
+func TestNewTemplateError(t *testing.T) {
+	te := newTemplateError(errors.New(`template: users/list.html:12:5: executing "users/list.html" at <.foo>: error`), nil, "views")
+	assert.Equal(t, "users/list.html", te.Name)
+	assert.Equal(t, filepath.Join("views", "users", "list.html"), te.File)
+	assert.Equal(t, 12, te.Line)
+	assert.Equal(t, 5, te.Column)
+	assert.Equal(t, `executing "users/list.html" at <.foo>: error`, te.Message)
+
+	te = newTemplateError(errors.New("something wrong"), nil, "views")
+	assert.Equal(t, "", te.Name)
+	assert.Equal(t, "something wrong", te.Message)
+	assert.Nil(t, te.Snippet(3))
+}
+
+func TestHTMLFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/hello.html":      {Data: []byte("<h1>Hello, {{ .name }}</h1>")},
+		"views/layout.html":     {Data: []byte("<main>{{ content }}</main>")},
+		"views/users/list.html": {Data: []byte("{{ range .users }}<li>{{ . }}</li>{{ end }}")},
+		"views/readme.txt":      {Data: []byte("{{ ignored")},
+		"other.html":            {Data: []byte("{{ ignored")},
+	}
+
+	view := HTMLFS(fsys, "views", ".html")
+	assert.Nil(t, view.Load())
+
+	res := httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "hello.html", "layout.html", Map{"name": "world"}, &Context{}))
+	assert.Equal(t, "<main><h1>Hello, world</h1></main>", res.Body.String())
+
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "users/list.html", "", Map{"users": []string{"a", "b"}}, &Context{}))
+	assert.Equal(t, "<li>a</li><li>b</li>", res.Body.String())
+
+	assert.NotNil(t, HTMLFS(fsys, "../views", ".html").Load())
+}
+
+func TestHTMLEngine_NestedLayout(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.html":  {Data: []byte(`<title>{{ yield "title" "Site" }}</title>{{ if hasSection "sidebar" }}<aside>{{ yield "sidebar" }}</aside>{{ end }}<main>{{ content }}</main>`)},
+		"admin.html": {Data: []byte(`{{ extends "base.html" }}{{ define "sidebar" }}menu{{ end }}<div class="admin">{{ content }}</div>`)},
+		"users.html": {Data: []byte(`{{ define "title" }}Users{{ end }}<h1>{{ .name }}</h1>`)},
+		"posts.html": {Data: []byte(`{{ extends "admin.html" }}{{ define "sidebar" }}posts{{ end }}<h1>Posts</h1>`)},
+		"loop.html":  {Data: []byte(`{{ extends "loop.html" }}{{ content }}`)},
+	}
+	view := HTMLFS(fsys, ".", ".html")
+	assert.Nil(t, view.Load())
+
+	res := httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "users.html", "base.html", Map{"name": "<b>"}, &Context{}))
+	assert.Equal(t, `<title>Users</title><main><h1>&lt;b&gt;</h1></main>`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "users.html", "admin.html", Map{"name": "a"}, &Context{}))
+	assert.Equal(t, `<title>Users</title><aside>menu</aside><main><div class="admin"><h1>a</h1></div></main>`, res.Body.String())
+
+	// 视图中的 extends 优先，视图中的区块覆盖布局中的区块
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "posts.html", "", nil, &Context{}))
+	assert.Equal(t, `<title>Site</title><aside>posts</aside><main><div class="admin"><h1>Posts</h1></div></main>`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "posts.html", NoLayout, nil, &Context{}))
+	assert.Equal(t, `<h1>Posts</h1>`, res.Body.String())
+
+	assert.EqualError(t, view.Render(httptest.NewRecorder(), "users.html", "missing.html", nil, &Context{}), "layout: missing.html not found")
+	assert.NotNil(t, view.Render(httptest.NewRecorder(), "users.html", "loop.html", nil, &Context{}))
+}
+
+func TestHTMLEngine_Partial(t *testing.T) {
+	fsys := fstest.MapFS{
+		"users.html":          {Data: []byte(`{{ range .users }}{{ partial "partials/user.html" . }}{{ end }}`)},
+		"partials/user.html":  {Data: []byte(`<li>{{ .Name }}</li>`)},
+		"missing.html":        {Data: []byte(`{{ partial "partials/none.html" }}`)},
+		"default.html":        {Data: []byte(`{{ yield "title" "<Home>" }}`)},
+		"partials/empty.html": {Data: []byte(`{{ .name }}`)},
+	}
+	view := HTMLFS(fsys, ".", ".html")
+	assert.Nil(t, view.Load())
+
+	res := httptest.NewRecorder()
+	data := Map{"users": []struct{ Name string }{{"a"}, {"<b>"}}}
+	assert.Nil(t, view.Render(res, "users.html", "", data, &Context{}))
+	assert.Equal(t, `<li>a</li><li>&lt;b&gt;</li>`, res.Body.String())
+
+	res = httptest.NewRecorder()
+	assert.Nil(t, view.Render(res, "default.html", "", nil, &Context{}))
+	assert.Equal(t, `&lt;Home&gt;`, res.Body.String())
+
+	err := view.Render(httptest.NewRecorder(), "missing.html", "", nil, &Context{})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "partial: partials/none.html not found")
+}
+
+func TestRouter_LayoutChain(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.html":  {Data: []byte(`<body>{{ content }}</body>`)},
+		"admin.html": {Data: []byte(`{{ extends "base.html" }}<div>{{ content }}</div>`)},
+		"index.html": {Data: []byte(`index`)},
+	}
+	app := New()
+	app.RegisterView(HTMLFS(fsys, ".", ".html"))
+
+	admin := app.Group("/admin")
+	admin.Layout("admin.html")
+	admin.GET("/", func(c *Context) error {
+		return c.View("index.html")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/admin/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, `<body><div>index</div></body>`, res.Body.String())
+}
+
+func TestHTMLEngine_RenderBuffered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`<main>{{ content }}</main>`)},
+		"broken.html": {Data: []byte(`<h1>start</h1>{{ .name.foo }}<p>end</p>`)},
+	}
+	app := New()
+	_ = app.RegisterView(HTMLFS(fsys, ".", ".html"))
+
+	var code int
+	app.Error(func(c *Context, error string, status int) {
+		code = status
+		_ = c.Text("error page")
+	})
+	app.GET("/broken", func(c *Context) error {
+		c.Layout("layout.html")
+		return c.View("broken.html", Map{"name": "a"})
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/broken", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	assert.Equal(t, "error page", res.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", res.Header().Get("Content-Type"))
+}
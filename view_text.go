@@ -0,0 +1,89 @@
+package potgo
+
+import (
+	"io/fs"
+	"os"
+	"text/template"
+	"text/template/parse"
+)
+
+// TextEngine 纯文本引擎，使用 text/template，不会转义输出，例如纯文本邮件
+//
+// 与 HTMLEngine 使用相同的布局、extends、区块、yield、partial 和 Reload
+type TextEngine struct {
+	templateEngine
+}
+
+var _ LayoutEngine = &TextEngine{}
+
+// Text 创建纯文本引擎
+func Text(path string, extension string) *TextEngine {
+	v := TextFS(os.DirFS(path), ".", extension)
+	v.path = path
+	return v
+}
+
+// TextFS 使用 fs.FS 中 root 目录下的视图文件创建纯文本引擎
+func TextFS(fsys fs.FS, root string, extension string) *TextEngine {
+	v := new(TextEngine)
+	v.init(v, newTextTemplates, fsys, root, extension)
+	return v
+}
+
+// Func 设置视图函数
+func (v *TextEngine) Func(funcMap template.FuncMap) {
+	for name, value := range funcMap {
+		v.funcMap[name] = value
+	}
+}
+
+// ContentType 返回渲染结果的内容类型，在 HTML 布局中使用时视图的内容会被转义
+func (v *TextEngine) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// textTemplates 使用 text/template 的模板集合
+type textTemplates struct {
+	t *template.Template
+}
+
+func newTextTemplates(name, left, right string) viewTemplates {
+	return textTemplates{template.New(name).Delims(left, right)}
+}
+
+func (ts textTemplates) lookup(name string) viewTemplate {
+	if t := ts.t.Lookup(name); t != nil {
+		return t
+	}
+	return nil
+}
+
+func (ts textTemplates) parse(text string) ([]*parse.Tree, error) {
+	if _, err := ts.t.Parse(text); err != nil {
+		return nil, err
+	}
+	var trees []*parse.Tree
+	for _, t := range ts.t.Templates() {
+		if t.Tree != nil {
+			trees = append(trees, t.Tree)
+		}
+	}
+	return trees, nil
+}
+
+func (ts textTemplates) addParseTree(name string, tree *parse.Tree) error {
+	_, err := ts.t.AddParseTree(name, tree)
+	return err
+}
+
+func (ts textTemplates) funcs(funcMap map[string]interface{}) {
+	ts.t.Funcs(funcMap)
+}
+
+func (ts textTemplates) clone() (viewTemplates, error) {
+	t, err := ts.t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return textTemplates{t}, nil
+}
@@ -0,0 +1,145 @@
+package potgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTextEngine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mail/layout.txt":  {Data: []byte("Hi,\n{{ content }}\n-- {{ partial \"mail/sign.txt\" .site }}")},
+		"mail/welcome.txt": {Data: []byte("Welcome <{{ .name }}>, visit {{ route \"user\" \"id\" 1 }}")},
+		"mail/sign.txt":    {Data: []byte("{{ . }} team")},
+	}
+	app := New()
+	view := TextFS(fsys, ".", ".txt")
+	view.Layout("mail/layout.txt")
+	assert.Equal(t, ".txt", view.Extension())
+	assert.Nil(t, app.RegisterView(view))
+	app.GET("/users/{id}", func(c *Context) error { return nil }).Name("user")
+
+	s, err := app.RenderToString("mail/welcome.txt", "", Map{"name": "a&b", "site": "Potgo"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi,\nWelcome <a&b>, visit /users/1\n-- Potgo team", s)
+
+	s, err = app.RenderToString("mail/welcome.txt", NoLayout, Map{"name": "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Welcome <a>, visit /users/1", s)
+
+	res := httptest.NewRecorder()
+	assert.NotNil(t, view.Render(res, "missing.txt", "", nil, &Context{}))
+	assert.NotNil(t, view.Render(res, "mail/sign.txt", "none.txt", nil, &Context{}))
+	assert.Equal(t, "", res.Body.String())
+
+	assert.NotNil(t, TextFS(fstest.MapFS{"a.txt": {Data: []byte("{{ bad")}}, ".", ".txt").Load())
+}
+
+func TestTextEngine_StandardFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mail.txt": {Data: []byte(`{{ upper .name }} {{ number 2 1234.5 }} {{ query "q" }}`)},
+	}
+
+	// 没有开启时不能使用标准视图函数
+	assert.NotNil(t, TextFS(fsys, ".", ".txt").Load())
+
+	app := New()
+	view := TextFS(fsys, ".", ".txt")
+	view.StandardFuncs(true)
+	assert.Nil(t, app.RegisterView(view))
+
+	s, err := app.RenderToString("mail.txt", "", Map{"name": "a&b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "A&B 1,234.50 ", s)
+}
+
+func TestTextEngine_Sections(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.txt": {Data: []byte(`[{{ yield "title" "<untitled>" }}] {{ content }}{{ if hasSection "footer" }} | {{ yield "footer" }}{{ end }}`)},
+		"mail.txt":   {Data: []byte(`{{ extends "layout.txt" }}{{ define "title" }}<Welcome>{{ end }}Hi {{ .name }}`)},
+		"plain.txt":  {Data: []byte(`Bye`)},
+	}
+	app := New()
+	view := TextFS(fsys, ".", ".txt")
+	assert.Nil(t, app.RegisterView(view))
+
+	s, err := app.RenderToString("mail.txt", "", Map{"name": "a&b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "[<Welcome>] Hi a&b", s)
+
+	// yield 的默认值不转义
+	s, err = app.RenderToString("plain.txt", "layout.txt", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "[<untitled>] Bye", s)
+
+	// 重新加载修改过的视图
+	view.Reload(true)
+	fsys["plain.txt"] = &fstest.MapFile{Data: []byte(`Bye!`), ModTime: time.Now()}
+	s, err = app.RenderToString("plain.txt", "layout.txt", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "[<untitled>] Bye!", s)
+}
+
+func TestContext_ViewContentType(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mail.txt":  {Data: []byte("Hi {{ .name }}")},
+		"page.html": {Data: []byte("Hi {{ .name }}")},
+	}
+	app := New()
+	assert.Nil(t, app.RegisterView(HTMLFS(fsys, ".", ".html")))
+	assert.Nil(t, app.RegisterView(TextFS(fsys, ".", ".txt")))
+	app.GET("/{name}", func(c *Context) error {
+		return c.View(c.Param("name"), Map{"name": "<script>"})
+	})
+
+	for name, want := range map[string][2]string{
+		"mail.txt":  {"text/plain; charset=utf-8", "Hi <script>"},
+		"page.html": {"text/html; charset=utf-8", "Hi &lt;script&gt;"},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "/"+name, nil)
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, req)
+		assert.Equal(t, want[0], res.Header().Get("Content-Type"), name)
+		assert.Equal(t, want[1], res.Body.String(), name)
+	}
+}
+
+// plainEngine 只实现 ViewEngine 的视图引擎
+type plainEngine struct{}
+
+func (plainEngine) Load() error { return nil }
+
+func (plainEngine) Render(w io.Writer, name string, layout string, data interface{}, c *Context) error {
+	_, err := io.WriteString(w, "<b>"+name+"</b>")
+	return err
+}
+
+func TestApplication_RegisterViewExtension(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.txt": {Data: []byte("[{{ content }}]")},
+	}
+	app := New()
+	assert.Nil(t, app.RegisterView(plainEngine{}))
+	assert.Nil(t, app.RegisterView(TextFS(fsys, ".", ".txt"), ".txt", ".text"))
+
+	// 没有实现 ExtensionEngine 时扩展名为 .html
+	s, err := app.RenderToString("a.html", "", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "<b>a.html</b>", s)
+
+	assert.Equal(t, app.views[".txt"], app.views[".text"])
+
+	// 没有实现 ContentTypeEngine 时内容类型为 text/html
+	app.GET("/", func(c *Context) error {
+		return c.View("b.html")
+	})
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Equal(t, "<b>b.html</b>", res.Body.String())
+}